@@ -18,15 +18,16 @@ This command sets terminal colors optimized for Claude Code sessions,
 using a palette of blues and purples with appropriate contrast for
 terminal readability.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cm := internal.NewColorManager()
+		cm := newColorManager()
 		color := cm.GenerateClaudeTheme()
 		
-		if err := cm.SetITermColor(color); err != nil {
+		if err := cm.ApplyColor(color, internal.ColorChange{Source: "claude"}); err != nil {
 			fmt.Fprintf(os.Stderr, "Error setting color: %v\n", err)
 			os.Exit(1)
 		}
 		
 		fmt.Printf("🤖 Applied Claude Code theme: RGB(%d, %d, %d)\n", color.R, color.G, color.B)
+		printSeed(cm, "--mode claude")
 	},
 }
 
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"color/internal"
 
@@ -19,7 +20,17 @@ Available modes:
   brightness   - Adjust brightness/value
   saturation   - Adjust color saturation
   complement   - Use complementary color
-  random       - Random mode selection`,
+  random       - Random mode selection
+
+Harmony modes walk a fixed set of colors relative to a remembered base
+color, one step per invocation:
+  analogous            - Neighbouring hues (±30°)
+  triadic              - Three evenly spaced hues
+  split_complementary  - Base plus the two hues beside its complement
+  tetradic             - Two complementary pairs
+  monochromatic        - Same hue at different brightness
+
+Use --list to preview a harmony set (or all of them) as swatches.`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		mode := "hue_shift"
@@ -27,6 +38,14 @@ Available modes:
 			mode = args[0]
 		}
 		
+		if list, _ := cmd.Flags().GetBool("list"); list {
+			if err := listHarmonies(args); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		
 		if err := cycleColors(mode); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -41,21 +60,34 @@ func cycleColors(mode ...string) error {
 		selectedMode = mode[0]
 	}
 	
-	cm := internal.NewColorManager()
+	cm := newColorManager()
 	
-	// Get current color
-	current, err := cm.GetCurrentColor()
+	// Get current color at full precision so repeated cycling doesn't drift
+	current, err := cm.GetCurrentColor16()
 	if err != nil {
 		return fmt.Errorf("failed to get current color: %w", err)
 	}
 	
+	// Harmony modes step through a remembered set
+	if internal.IsHarmonyMode(selectedMode) {
+		precise, step, total := cm.NextHarmonyColor(current, selectedMode)
+		if err := cm.ApplyColor16(precise, internal.ColorChange{Source: "cycle", Mode: selectedMode}); err != nil {
+			return fmt.Errorf("failed to set color: %w", err)
+		}
+		newColor := precise.To8()
+		fmt.Printf("🎼 %s harmony %d/%d: RGB(%d, %d, %d)\n",
+			harmonyTitle(selectedMode), step, total, newColor.R, newColor.G, newColor.B)
+		return nil
+	}
+	
 	// Generate variant
-	newColor := cm.GenerateVariant(current, selectedMode)
+	precise := cm.GenerateVariant16(current, selectedMode)
 	
 	// Set new color
-	if err := cm.SetITermColor(newColor); err != nil {
+	if err := cm.ApplyColor16(precise, internal.ColorChange{Source: "cycle", Mode: selectedMode}); err != nil {
 		return fmt.Errorf("failed to set color: %w", err)
 	}
+	newColor := precise.To8()
 	
 	// More human-friendly messages
 	var message string
@@ -74,10 +106,59 @@ func cycleColors(mode ...string) error {
 	
 	fmt.Printf("%s: RGB(%d, %d, %d)\n", 
 		message, newColor.R, newColor.G, newColor.B)
+	printSeed(cm, fmt.Sprintf("--mode %s %s", selectedMode, current.Hex()))
 	
 	return nil
 }
 
+// listHarmonies previews harmony sets relative to the remembered base color
+func listHarmonies(args []string) error {
+	modes := internal.HarmonyModes
+	if len(args) > 0 {
+		if !internal.IsHarmonyMode(args[0]) {
+			return fmt.Errorf("%s is not a harmony mode", args[0])
+		}
+		modes = args[:1]
+	}
+	
+	cm := newColorManager()
+	current, err := cm.GetCurrentColor16()
+	if err != nil {
+		return fmt.Errorf("failed to get current color: %w", err)
+	}
+	
+	for _, mode := range modes {
+		base := cm.HarmonyBase(current, mode)
+		fmt.Printf("%s:\n", harmonyTitle(mode))
+		marked := false
+		for i, precise := range cm.HarmonySet16(base, mode) {
+			color := precise.To8()
+			marker := "  "
+			if precise == current && !marked {
+				marker, marked = "▶ ", true
+			}
+			fmt.Printf("  %s%d. %s RGB(%d, %d, %d)\n", marker, i+1, swatch(color), color.R, color.G, color.B)
+		}
+	}
+	
+	return nil
+}
+
+// harmonyTitle returns a display name for a harmony mode
+func harmonyTitle(mode string) string {
+	words := strings.Split(mode, "_")
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, "-")
+}
+
+// swatch renders a color block using a 24-bit ANSI background
+func swatch(color internal.RGB) string {
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%dm      \x1b[0m", color.R, color.G, color.B)
+}
+
 func init() {
+	cycleCmd.Flags().Bool("list", false, "Preview harmony sets as swatches")
 	rootCmd.AddCommand(cycleCmd)
 }
\ No newline at end of file
@@ -18,6 +18,32 @@ Each directory gets a unique, consistent color based on its path.
 The same directory will always produce the same color, providing
 visual consistency for navigation.
 
+A .colorrc file in the directory or any parent can fix the color,
+declare a palette, or change the generator ranges. Nearer files
+override farther ones. Untrusted files are skipped until approved
+with 'color trust'.
+
+Inside a git repository (including worktrees and submodules) the
+project root is hashed instead, so the whole project keeps one color.
+With [branch] enabled in the configuration, protected branches,
+detached HEADs and rebases or merges in progress get a warning tint,
+and feature branches a subtle hue offset derived from their name.
+
+While a configured danger zone is active (for example a production
+Kubernetes context or AWS profile), its alert color replaces the
+directory color everywhere.
+
+In an SSH session the hostname's color from [[hosts]] rules replaces
+the directory color, or blends with it when [ssh] blend is below 1.
+
+With strategy = "hierarchical" in [directory], subdirectories derive
+their color from their parent instead: the same hue family, offset by
+an amount derived from each directory's own name.
+
+Paths are canonicalized first: ~ is expanded, relative paths are made
+absolute and symlinks are resolved, so every spelling of a directory
+shares one color.
+
 If no path is provided, uses current working directory.`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -26,10 +52,10 @@ If no path is provided, uses current working directory.`,
 			path = args[0]
 		}
 		
-		cm := internal.NewColorManager()
+		cm := newColorManager()
 		color := cm.GenerateDirectoryTheme(path)
 		
-		if err := cm.SetITermColor(color); err != nil {
+		if err := cm.ApplyColor(color, internal.ColorChange{Source: "directory", Path: path}); err != nil {
 			fmt.Fprintf(os.Stderr, "Error setting color: %v\n", err)
 			os.Exit(1)
 		}
@@ -42,9 +68,49 @@ If no path is provided, uses current working directory.`,
 				actualPath = "current directory"
 			}
 		}
+		actualPath = internal.CanonicalPath(actualPath)
+		
+		// A theme named by a .colorrc or rule also brings its palette
+		theme, themed := cm.DirectoryTheme(actualPath)
+		if themed {
+			if err := cm.SetThemeColors(theme); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to set theme colors: %v\n", err)
+			}
+		}
 		
 		fmt.Printf("📁 Applied color for %s: RGB(%d, %d, %d)\n", 
 			actualPath, color.R, color.G, color.B)
+		
+		if match, ok := cm.ActiveDangerZone(); ok {
+			fmt.Printf("🚨 Danger zone: %s\n", match.Reason())
+		}
+		
+		pin, pinned := cm.PinFor(actualPath)
+		if pinned {
+			fmt.Printf("📌 Pinned on %s\n", pin.Path)
+		} else if key, _ := cm.ProjectKey(actualPath); key != actualPath {
+			fmt.Printf("🌿 Colored as git project %s\n", key)
+		}
+		if !pinned && cm.Config().Strategy == internal.StrategyHierarchical {
+			if anchor, names := cm.HierarchyAnchor(actualPath); len(names) > 0 {
+				fmt.Printf("🌳 Derived from %s\n", anchor)
+			}
+		}
+		if _, tint := cm.BranchTint(actualPath); tint != "" {
+			fmt.Printf("🔀 Tinted for %s\n", tint)
+		}
+		
+		if themed {
+			fmt.Printf("🎨 Using theme %s\n", theme.Name)
+		}
+		
+		settings := cm.DirectorySettings(actualPath)
+		for _, source := range settings.Sources {
+			fmt.Printf("📄 Using %s\n", source)
+		}
+		for _, skipped := range settings.Untrusted {
+			fmt.Printf("🔒 Skipped untrusted %s (run: color trust %s)\n", skipped, skipped)
+		}
 	},
 }
 
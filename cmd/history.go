@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"color/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show recently applied colors",
+	Long: `Show the colors applied to the terminal, newest first.
+
+Every applied color is recorded with its time, source, mode, directory,
+storage backend, the color before and after, and the command it was
+applied for by 'color wrap' or 'color ssh'. The newest
+persistence.history_limit events are kept.
+
+--since takes a duration such as 90m, 24h or 7d, or a date such as
+2026-10-01. --path matches the directory and everything below it.
+
+Example:
+  color history
+  color history --since 24h --source cycle
+  color history --path ~/work --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		filter, err := historyFilter(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cm := newColorManager()
+		events, err := cm.ColorHistory(filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			if events == nil {
+				events = []internal.ColorEvent{}
+			}
+			data, _ := json.MarshalIndent(events, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		if len(events) == 0 {
+			fmt.Println("No colors recorded")
+			return
+		}
+		for _, event := range events {
+			fmt.Println(formatEvent(event))
+		}
+	},
+}
+
+// historyFilter builds the event filter from the command's flags
+func historyFilter(cmd *cobra.Command) (internal.EventFilter, error) {
+	var filter internal.EventFilter
+	var err error
+
+	if since, _ := cmd.Flags().GetString("since"); since != "" {
+		if filter.Since, err = parseSince(since, time.Now()); err != nil {
+			return filter, err
+		}
+	}
+	if source, _ := cmd.Flags().GetString("source"); source != "" {
+		if filter.Source, err = internal.ParseEventSource(source); err != nil {
+			return filter, err
+		}
+	}
+	filter.Path, _ = cmd.Flags().GetString("path")
+	if filter.Limit, _ = cmd.Flags().GetInt("limit"); filter.Limit < 0 {
+		return filter, fmt.Errorf("invalid limit: must be 0 or more")
+	}
+	return filter, nil
+}
+
+// parseSince reads a duration before now, in days with a d suffix, or a
+// date or RFC 3339 time
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: expected a duration like 24h or 7d, or a date like 2006-01-02", value)
+}
+
+// formatEvent describes an event on one line
+func formatEvent(event internal.ColorEvent) string {
+	change := fmt.Sprintf("%s %s", swatch(event.New.To8()), event.New.To8().Hex())
+	if event.Old != nil {
+		change = fmt.Sprintf("%s %s → %s", swatch(event.Old.To8()), event.Old.To8().Hex(), change)
+	}
+
+	source := event.Source
+	if event.Mode != "" {
+		source += " " + event.Mode
+	}
+	line := fmt.Sprintf("%s  %-22s %s  %s", event.Time.Local().Format("2006-01-02 15:04:05"), source, change, event.Path)
+	if event.Command != "" {
+		line += fmt.Sprintf(" (%s)", event.Command)
+	}
+	return line
+}
+
+func init() {
+	historyCmd.Flags().String("since", "", "Only events after a duration ago or a date")
+	historyCmd.Flags().String("source", "", "Only events from one source: "+strings.Join(internal.EventSources, ", "))
+	historyCmd.Flags().String("path", "", "Only events in a directory or below it")
+	historyCmd.Flags().Int("limit", 20, "Show at most this many events; 0 for all")
+	historyCmd.Flags().Bool("json", false, "Print events as JSON")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 30, 0, 0, time.Local)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"7d", time.Date(2026, 10, 8, 12, 30, 0, 0, time.Local)},
+		{"0d", now},
+		{"30d", time.Date(2026, 9, 15, 12, 30, 0, 0, time.Local)},
+		{"24h", now.Add(-24 * time.Hour)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"2026-10-01", time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)},
+		{"2026-10-01T08:00:00Z", time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.value, now)
+		if err != nil {
+			t.Errorf("parseSince(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "d", "-7d", "-1h", "7w", "2026-13-01", "yesterday"} {
+		if got, err := parseSince(value, now); err == nil {
+			t.Errorf("parseSince(%q) = %v, want an error", value, got)
+		}
+	}
+}
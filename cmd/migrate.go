@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Merge stored directory colors under canonical paths",
+	Long: `Merge stored directory colors whose paths name the same directory.
+
+Older versions stored colors under the path exactly as given, so ~/proj,
+/home/me/proj/ and a symlink to it each had their own entry. This
+rewrites every stored directory color to its canonical path; when
+several entries collide, the most recently used color wins.
+
+The migration runs once. Use --force to run it again.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		force, _ := cmd.Flags().GetBool("force")
+
+		cm := newColorManager()
+		result, err := cm.MigrateDirectoryKeys(force)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Migration failed: %v\n", err)
+			os.Exit(1)
+		}
+
+		if result.AlreadyDone {
+			fmt.Println("✅ Directory colors were already migrated (use --force to run again)")
+			return
+		}
+		fmt.Printf("✅ Moved %d directory colors to canonical paths, merged %d duplicates\n",
+			result.Rewritten, result.Merged)
+		for _, path := range result.Skipped {
+			fmt.Printf("⏭️ Skipped relative path %s\n", path)
+		}
+	},
+}
+
+func init() {
+	migrateCmd.Flags().Bool("force", false, "Run the migration even if it ran before")
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"color/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var pinCmd = &cobra.Command{
+	Use:   "pin [path] [color]",
+	Short: "Pin a directory's color permanently",
+	Long: `Pin a directory's color so it never expires.
+
+Stored directory colors expire after a while, so a rarely visited
+directory can come back with a new color. A pinned color is kept until
+'color unpin', survives 'color clear' (unless --all) and overrides
+.colorrc files and rules.
+
+Without a color, the directory's current color is pinned. Colors are
+given for the dark appearance, like .colorrc colors. With --subtree
+every directory below shares the pin, unless it has a pin of its own.
+
+Example:
+  color pin
+  color pin ~/work/api '#1e3a5f'
+  color pin --subtree ~/work/infra`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		list, _ := cmd.Flags().GetBool("list")
+		subtree, _ := cmd.Flags().GetBool("subtree")
+		cm := newColorManager()
+
+		if list {
+			listPins(cm)
+			return
+		}
+
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+		var color *internal.RGB16
+		if len(args) > 1 {
+			parsed, err := internal.ParseColor16(args[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			color = &parsed
+		}
+
+		pinned, err := cm.PinDirectory(path, color, subtree)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		scope := ""
+		if subtree {
+			scope = " and everything below"
+		}
+		fmt.Printf("📌 Pinned %s%s to %s %s\n", internal.CanonicalPath(path), scope, swatch(pinned.To8()), pinned.To8().Hex())
+
+		// Show the pinned color right away when pinning the current directory
+		if cwd, err := os.Getwd(); err == nil && internal.CanonicalPath(cwd) == internal.CanonicalPath(path) {
+			if err := cm.ApplyColor16(cm.GenerateDirectoryTheme(cwd).To16(), internal.ColorChange{Source: "pin", Path: cwd}); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to set color: %v\n", err)
+			}
+		}
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin [path]",
+	Short: "Remove a directory's pinned color",
+	Long: `Remove the pin of a directory, letting its color be generated and
+expire again. Subtree pins are removed from the directory they were
+made on.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		cm := newColorManager()
+		removed, err := cm.UnpinDirectory(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		path = internal.CanonicalPath(path)
+		if !removed {
+			if pin, ok := cm.PinFor(path); ok {
+				fmt.Printf("ℹ️ %s is covered by the subtree pin on %s\n", path, pin.Path)
+			} else {
+				fmt.Printf("ℹ️ %s isn't pinned\n", path)
+			}
+			return
+		}
+		fmt.Printf("📍 Unpinned %s\n", path)
+	},
+}
+
+// listPins prints every pinned directory
+func listPins(cm *internal.ColorManager) {
+	pins, err := cm.ListPins()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(pins) == 0 {
+		fmt.Println("No pinned directories")
+		return
+	}
+	for _, pin := range pins {
+		color := pin.Entry.Color16()
+		scope := ""
+		if pin.Entry.Subtree {
+			scope = " (subtree)"
+		}
+		fmt.Printf("%s %s %s%s\n", swatch(color.To8()), color.To8().Hex(), pin.Path, scope)
+	}
+}
+
+func init() {
+	pinCmd.Flags().Bool("subtree", false, "Also pin every directory below")
+	pinCmd.Flags().Bool("list", false, "List pinned directories")
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"color/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var rerollCmd = &cobra.Command{
+	Use:   "reroll [path]",
+	Short: "Pick a new color for a directory",
+	Long: `Replace a directory's color with a new random one and apply it.
+
+The new color is chosen to be perceptually far from the directory's
+previous colors and from the colors of its sibling directories. Inside
+a git project the whole project is rerolled.
+
+The last 10 colors are remembered; --back returns to the one before the
+latest reroll. Directories colored by a pin, .colorrc or rule can't be
+rerolled.
+
+If no path is provided, uses current working directory.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		back, _ := cmd.Flags().GetBool("back")
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		cm := newColorManager()
+		var result internal.RerollResult
+		var err error
+		if back {
+			result, err = cm.RerollBack(path)
+		} else {
+			result, err = cm.RerollDirectory(path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := cm.ApplyColor(cm.GenerateDirectoryTheme(path), internal.ColorChange{Source: "reroll", Path: path}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting color: %v\n", err)
+			os.Exit(1)
+		}
+
+		verb := "Rerolled"
+		if back {
+			verb = "Restored"
+		}
+		fmt.Printf("🎲 %s %s: %s %s → %s %s\n", verb, result.Key,
+			swatch(result.Previous.To8()), result.Previous.To8().Hex(),
+			swatch(result.Color.To8()), result.Color.To8().Hex())
+		if result.Siblings > 0 {
+			fmt.Printf("   Kept apart from %d sibling directories\n", result.Siblings)
+		}
+		fmt.Printf("   %d earlier colors to go back to\n", result.History)
+	},
+}
+
+func init() {
+	rerollCmd.Flags().Bool("back", false, "Return to the color before the last reroll")
+	rootCmd.AddCommand(rerollCmd)
+}
@@ -11,24 +11,25 @@ import (
 
 var resetCmd = &cobra.Command{
 	Use:   "reset",
-	Short: "Reset terminal to default dark theme",
-	Long: `Reset the terminal background to a default dark theme.
+	Short: "Reset terminal to default theme",
+	Long: `Reset the terminal background to a default theme.
 	
 This command restores the terminal to a standard dark background
-color suitable for general terminal use.`,
+color suitable for general terminal use, or a standard light
+background when --appearance light is active.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cm := internal.NewColorManager()
+		cm := newColorManager()
 		
-		// Default dark theme
-		defaultColor := internal.RGB{R: 30, G: 30, B: 30}
+		// Default theme for the active appearance
+		defaultColor := cm.ResetColor()
 		
-		if err := cm.SetITermColor(defaultColor); err != nil {
+		if err := cm.ApplyColor(defaultColor, internal.ColorChange{Source: "reset", Mode: string(cm.Appearance())}); err != nil {
 			fmt.Fprintf(os.Stderr, "Error setting color: %v\n", err)
 			os.Exit(1)
 		}
 		
-		fmt.Printf("🔄 Reset to default dark theme: RGB(%d, %d, %d)\n", 
-			defaultColor.R, defaultColor.G, defaultColor.B)
+		fmt.Printf("🔄 Reset to default %s theme: RGB(%d, %d, %d)\n", 
+			cm.Appearance(), defaultColor.R, defaultColor.G, defaultColor.B)
 	},
 }
 
@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"color/internal"
 
@@ -22,10 +25,10 @@ This CLI provides automatic terminal color changes based on:
 Built with Go and Cobra for speed and reliability.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Default action - apply directory color and show help
-		cm := internal.NewColorManager()
+		cm := newColorManager()
 		color := cm.GenerateDirectoryTheme("")
 		
-		if err := cm.SetITermColor(color); err != nil {
+		if err := cm.ApplyColor(color, internal.ColorChange{Source: "directory"}); err != nil {
 			fmt.Fprintf(os.Stderr, "Error setting color: %v\n", err)
 		} else {
 			cwd, _ := os.Getwd()
@@ -38,10 +41,120 @@ Built with Go and Cobra for speed and reliability.`,
 	},
 }
 
+// Global settings shared by all commands
+var (
+	config = internal.DefaultConfig()
+	seed   *int64 // nil unless --seed or COLOR_SEED was given
+)
+
+// flagOrEnv returns a flag's value, falling back to an environment variable
+// when the flag was not given explicitly
+func flagOrEnv(cmd *cobra.Command, name, env string) string {
+	value := cmd.Flags().Lookup(name).Value.String()
+	if !cmd.Flags().Changed(name) {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	return value
+}
+
+// parseGlobalFlags loads the configuration file and applies persistent flags
+// on top of it before any command runs
+func parseGlobalFlags(cmd *cobra.Command, args []string) error {
+	loaded, err := internal.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	config = loaded
+
+	flags := cmd.Flags()
+	if flags.Changed("appearance") {
+		name, _ := flags.GetString("appearance")
+		if config.Appearance, err = internal.ParseAppearance(name); err != nil {
+			return err
+		}
+	}
+	if flags.Changed("transition") {
+		config.Transition.Enabled, _ = flags.GetBool("transition")
+	}
+	if flags.Changed("transition-duration") {
+		config.Transition.Duration, _ = flags.GetDuration("transition-duration")
+	}
+	if flags.Changed("transition-fps") {
+		if config.Transition.FPS, _ = flags.GetInt("transition-fps"); config.Transition.FPS <= 0 {
+			return fmt.Errorf("invalid transition frame rate: must be a positive integer")
+		}
+	}
+
+	seed = nil
+	if value := flagOrEnv(cmd, "seed", "COLOR_SEED"); flags.Changed("seed") || os.Getenv("COLOR_SEED") != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid seed %q: must be an integer", value)
+		}
+		seed = &parsed
+	}
+	return nil
+}
+
+// newColorManager creates a color manager configured from the config file
+// and global flags
+func newColorManager() *internal.ColorManager {
+	cm := internal.NewColorManager(config)
+	if seed != nil {
+		cm.SetSeed(*seed)
+	}
+	if isInteractive() {
+		cm.SetTrustPrompt(promptTrust)
+	}
+	return cm
+}
+
+// isInteractive reports whether the user can answer prompts
+func isInteractive() bool {
+	for _, f := range []*os.File{os.Stdin, os.Stderr} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// promptTrust shows an untrusted .colorrc and asks whether to use it
+func promptTrust(path string, data []byte) bool {
+	fmt.Fprintf(os.Stderr, "🔒 %s is not trusted yet. It contains:\n", path)
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		fmt.Fprintf(os.Stderr, "    %s\n", line)
+	}
+	fmt.Fprint(os.Stderr, "Trust this file? [y/N] ")
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// printSeed reports the seed behind a random color so it can be recreated
+func printSeed(cm *internal.ColorManager, recreate string) {
+	if cm.RandomnessUsed() {
+		fmt.Printf("🎲 Seed %d (recreate with: color set --seed %d %s)\n", cm.Seed(), cm.Seed(), recreate)
+	}
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
 
 func init() {
+	rootCmd.PersistentPreRunE = parseGlobalFlags
+	// Errors are printed by main; usage is noise for configuration errors
+	rootCmd.SilenceUsage = true
+	rootCmd.SilenceErrors = true
+	rootCmd.PersistentFlags().String("appearance", string(config.Appearance), "Color appearance: dark or light (env COLOR_APPEARANCE)")
+	rootCmd.PersistentFlags().Bool("transition", config.Transition.Enabled, "Animate between colors (env COLOR_TRANSITION)")
+	rootCmd.PersistentFlags().Duration("transition-duration", config.Transition.Duration, "Length of animated transitions (env COLOR_TRANSITION_DURATION)")
+	rootCmd.PersistentFlags().Int("transition-fps", config.Transition.FPS, "Frame rate of animated transitions (env COLOR_TRANSITION_FPS)")
+	rootCmd.PersistentFlags().Int64("seed", 0, "Seed for reproducible random colors (env COLOR_SEED)")
 	rootCmd.Flags().BoolP("version", "v", false, "Show version information")
 }
\ No newline at end of file
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"color/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Inspect path-pattern color rules",
+	Long: `Inspect the [[rules]] declared in the configuration file.
+
+Rules map glob or regex path patterns to fixed colors, palettes or
+generator options. 'color directory' consults them before falling back
+to the path hash. Rules are evaluated by descending priority, then file
+order; every matching rule contributes settings (higher priorities win)
+until a matching rule with stop = true ends the evaluation.`,
+}
+
+var rulesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List rules in evaluation order",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(config.Rules) == 0 {
+			fmt.Println("No rules configured")
+			return
+		}
+
+		for i, rule := range config.Rules {
+			fmt.Printf("%d. %s\n", i+1, describeRule(rule))
+		}
+	},
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test <path>",
+	Short: "Show which rules match a path",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := internal.CanonicalPath(args[0])
+		cm := newColorManager()
+		matched := cm.MatchRules(path)
+
+		fmt.Printf("🧭 Rules for %s:\n", path)
+		if len(matched) == 0 {
+			fmt.Println("  No rule matched")
+		}
+		for _, rule := range matched {
+			fmt.Printf("  ✅ %s\n", describeRule(rule))
+		}
+		if n := len(matched); n > 0 && matched[n-1].Stop {
+			fmt.Println("  ⏹  Evaluation stopped")
+		}
+
+		color := cm.GenerateDirectoryTheme(path)
+		fmt.Printf("Result: %s RGB(%d, %d, %d)\n", swatch(color), color.R, color.G, color.B)
+	},
+}
+
+// describeRule summarizes a rule on one line
+func describeRule(rule internal.Rule) string {
+	parts := []string{fmt.Sprintf("priority %d", rule.Priority)}
+	parts = append(parts, describeSettings(rule.Settings)...)
+	if rule.Stop {
+		parts = append(parts, "stop")
+	}
+	return fmt.Sprintf("%s: %s", rule.Describe(), strings.Join(parts, ", "))
+}
+
+// describeSettings lists the overrides declared by directory settings
+func describeSettings(s internal.DirectorySettings) []string {
+	var parts []string
+	if s.Color != nil {
+		parts = append(parts, "color "+s.Color.Hex())
+	}
+	if s.LightColor != nil {
+		parts = append(parts, "light color "+s.LightColor.Hex())
+	}
+	if s.Theme != nil {
+		parts = append(parts, "theme "+*s.Theme)
+	}
+	if len(s.Palette) > 0 {
+		parts = append(parts, fmt.Sprintf("palette of %d", len(s.Palette)))
+	}
+	if s.Saturation != nil {
+		parts = append(parts, fmt.Sprintf("saturation %g-%g", s.Saturation.Min, s.Saturation.Max))
+	}
+	if s.Value != nil {
+		parts = append(parts, fmt.Sprintf("value %g-%g", s.Value.Min, s.Value.Max))
+	}
+	return parts
+}
+
+func init() {
+	rulesCmd.AddCommand(rulesListCmd)
+	rulesCmd.AddCommand(rulesTestCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
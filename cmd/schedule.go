@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"color/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Inspect time-of-day color schedules",
+	Long: `Inspect the [[schedule]] windows declared in the configuration file.
+
+Inside a window such as 20:00-07:00, generated directory, Claude and
+host colors are dimmed, warmed or muted. The first window covering the
+current time applies. Colors from color set, reset, cycle and theme
+apply are left as given.`,
+}
+
+var schedulePreviewCmd = &cobra.Command{
+	Use:   "preview [path]",
+	Short: "Show a directory's color across the day",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+		path = internal.CanonicalPath(path)
+
+		cm := newColorManager()
+		if len(cm.Config().Schedule) == 0 {
+			fmt.Println("No schedule windows configured")
+		}
+
+		fmt.Printf("🕰️ %s across the day:\n", path)
+		now := time.Now()
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		for hour := 0; hour < 24; hour++ {
+			t := day.Add(time.Duration(hour) * time.Hour)
+			color := cm.DirectoryThemeAt(path, t)
+
+			window := ""
+			if w, ok := cm.ScheduleAt(t); ok {
+				window = w.Describe()
+			}
+			fmt.Printf("  %s %s RGB(%3d, %3d, %3d) %s\n",
+				t.Format("15:04"), swatch(color), color.R, color.G, color.B, window)
+		}
+	},
+}
+
+func init() {
+	scheduleCmd.AddCommand(schedulePreviewCmd)
+	rootCmd.AddCommand(scheduleCmd)
+}
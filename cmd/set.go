@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"color/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set [color]",
+	Short: "Set an explicit or generated color",
+	Long: `Set the terminal background to an explicit color, or generate one.
+
+Colors can be written as #rrggbb, #rgb, #rrrrggggbbbb (16-bit),
+rgb(r, g, b) or r,g,b.
+
+With --mode, a variant of the given color (or the current terminal
+color) is generated using any 'color cycle' mode, or a Claude theme
+with --mode claude. Combined with --seed this recreates a random
+color printed by an earlier command.
+
+Example:
+  color set '#1e1e2e'
+  color set --seed 123456 --mode complement '#2a3b4c'
+  color set --seed 987654 --mode claude`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mode, _ := cmd.Flags().GetString("mode")
+		if len(args) == 0 && mode == "" {
+			fmt.Fprintln(os.Stderr, "Error: specify a color or --mode")
+			os.Exit(1)
+		}
+
+		if err := setColor(args, mode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// setColor applies an explicit color or a generated variant of it
+func setColor(args []string, mode string) error {
+	cm := newColorManager()
+
+	var base internal.RGB16
+	var err error
+	if len(args) > 0 {
+		if base, err = internal.ParseColor16(args[0]); err != nil {
+			return err
+		}
+	} else if mode != "claude" {
+		if base, err = cm.GetCurrentColor16(); err != nil {
+			return fmt.Errorf("failed to get current color: %w", err)
+		}
+	}
+
+	target := base
+	switch mode {
+	case "":
+	case "claude":
+		target = cm.GenerateClaudeTheme().To16()
+	default:
+		target = cm.GenerateVariant16(base, mode)
+	}
+
+	if err := cm.ApplyColor16(target, internal.ColorChange{Source: "set", Mode: mode}); err != nil {
+		return fmt.Errorf("failed to set color: %w", err)
+	}
+
+	color := target.To8()
+	fmt.Printf("🎨 Set color: RGB(%d, %d, %d)\n", color.R, color.G, color.B)
+	if mode == "claude" {
+		printSeed(cm, "--mode claude")
+	} else if mode != "" {
+		printSeed(cm, fmt.Sprintf("--mode %s %s", mode, base.Hex()))
+	}
+
+	return nil
+}
+
+func init() {
+	setCmd.Flags().String("mode", "", "Generate a variant (any cycle mode, or claude)")
+	rootCmd.AddCommand(setCmd)
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"color/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var sshCmd = &cobra.Command{
+	Use:   "ssh <host> [args...]",
+	Short: "Connect with ssh using the remote host's color",
+	Long: `Run ssh with the remote host's color applied.
+
+The host's color comes from the [[hosts]] rules in the configuration,
+or from a hash of its hostname. The local directory color is restored
+when the session exits, and ssh's exit code is preserved. Arguments
+after the host are passed to ssh unchanged.
+
+Example:
+  color ssh db-1.prod
+  color ssh deploy@web-2.staging -p 2222 uptime`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := sshCommand(args); err != nil {
+			if exitError, ok := err.(*exec.ExitError); ok {
+				if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
+					os.Exit(status.ExitStatus())
+				}
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// sshCommand runs ssh with the host's color, restoring the directory color
+func sshCommand(args []string) error {
+	cm := newColorManager()
+
+	host := internal.SSHHostName(args[0])
+	command := strings.Join(append([]string{"ssh"}, args...), " ")
+	if err := cm.ApplyColor(cm.GenerateHostTheme(host), internal.ColorChange{Source: "ssh", Command: command}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to set host color: %v\n", err)
+	}
+
+	execCmd := exec.Command("ssh", args...)
+	execCmd.Stdout = os.Stdout
+	execCmd.Stderr = os.Stderr
+	execCmd.Stdin = os.Stdin
+
+	err := execCmd.Run()
+
+	// Always restore the local color, even if the connection failed
+	if cwd, cwdErr := os.Getwd(); cwdErr == nil {
+		if restoreErr := cm.ApplyColor(cm.GenerateDirectoryTheme(cwd), internal.ColorChange{Source: "directory", Path: cwd, Command: command}); restoreErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to restore directory colors: %v\n", restoreErr)
+		}
+	}
+
+	return err
+}
+
+func init() {
+	// Flags after the host belong to ssh
+	sshCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(sshCmd)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"color/internal"
 
@@ -10,28 +11,47 @@ import (
 
 var statusCmd = &cobra.Command{
 	Use:   "status",
-	Short: "Show color persistence and Redis connection status",
+	Short: "Show color persistence and store status",
 	Long: `Display the current status of the color persistence system.
 	
 This command shows:
-- Redis connection status
+- Which store colors persist in, and whether it is reachable
 - Number of stored directory colors
 - Last Claude theme usage
-- Persistence configuration details`,
+- Persistence configuration details
+- The active danger zone, if any
+- The active schedule window, if any`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cm := internal.NewColorManager()
+		cm := newColorManager()
 		
 		// Get persistence status
 		status := cm.GetPersistenceStatus()
 		fmt.Println(status)
+		if n, err := cm.CountDirectoryColors(); err == nil {
+			fmt.Printf("🎨 Stored directory colors: %d\n", n)
+		}
+		
+		if path := cm.Config().Path; path != "" {
+			fmt.Printf("⚙️ Config: %s\n", path)
+		} else {
+			fmt.Printf("⚙️ Config: defaults (%s not found)\n", internal.ConfigPath())
+		}
+		fmt.Printf("🌓 Appearance: %s\n", cm.Appearance())
+		if host, ok := internal.RemoteHost(); ok && cm.Config().SSH.Enabled {
+			fmt.Printf("🖥️ Remote host: %s\n", host)
+		}
+		if match, ok := cm.ActiveDangerZone(); ok {
+			fmt.Printf("🚨 Danger zone: %s\n", match.Reason())
+		}
+		if window, ok := cm.ScheduleAt(time.Now()); ok {
+			fmt.Printf("🌙 Schedule: %s\n", window.Describe())
+		}
 		
 		// Show color history if available
-		if history, err := cm.GetColorHistory(5); err == nil && len(history) > 0 {
+		if history, err := cm.ColorHistory(internal.EventFilter{Limit: 5}); err == nil && len(history) > 0 {
 			fmt.Println("\n📊 Recent Color History:")
-			for i, entry := range history {
-				fmt.Printf("%d. RGB(%d, %d, %d) - %s (%s)\n", 
-					i+1, entry.Color.R, entry.Color.G, entry.Color.B, 
-					entry.Source, entry.Timestamp.Format("2006-01-02 15:04"))
+			for i, event := range history {
+				fmt.Printf("%d. %s\n", i+1, formatEvent(event))
 			}
 		}
 	},
@@ -40,27 +60,34 @@ This command shows:
 var clearCmd = &cobra.Command{
 	Use:   "clear",
 	Short: "Clear all stored color data",
-	Long: `Remove all stored colors from the Redis cache.
+	Long: `Remove all stored colors from the store.
 	
 This will clear:
 - All directory color associations
 - Last Claude theme color
 - Color history data
 	
-Colors will be regenerated on next use.`,
+Colors will be regenerated on next use. Pinned directory colors and
+saved themes are kept; --all removes pins too.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cm := internal.NewColorManager()
+		all, _ := cmd.Flags().GetBool("all")
+		cm := newColorManager()
 		
-		if err := cm.ClearColorCache(); err != nil {
+		if err := cm.ClearColorCache(all); err != nil {
 			fmt.Printf("❌ Error clearing color cache: %v\n", err)
 			return
 		}
 		
-		fmt.Println("🗑️ Cleared all stored color data")
+		if all {
+			fmt.Println("🗑️ Cleared all stored color data")
+		} else {
+			fmt.Println("🗑️ Cleared stored color data (pins and themes kept)")
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
+	clearCmd.Flags().Bool("all", false, "Also remove pinned directory colors")
 	rootCmd.AddCommand(clearCmd)
 }
\ No newline at end of file
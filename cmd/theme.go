@@ -0,0 +1,355 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"color/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var themeCmd = &cobra.Command{
+	Use:   "theme",
+	Short: "Save and apply named themes",
+	Long: `Manage named themes: a background, a foreground and the 16 ANSI
+palette colors.
+
+Themes persist in the store and never expire. Rules and .colorrc files
+can reference them with theme = "<name>" instead of a raw color.`,
+}
+
+var themeSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save the current colors as a theme",
+	Long: `Save the current colors as a named theme.
+
+With --from terminal (the default), the background, foreground and
+palette are read from the terminal. With --from last, the last color
+applied by this tool is saved with its matching foreground. When the
+terminal can't be read, the last applied color is used instead.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		from, _ := cmd.Flags().GetString("from")
+		cm := newColorManager()
+
+		var theme internal.Theme
+		var err error
+		switch from {
+		case "terminal":
+			if theme, err = cm.ReadITermTheme(); err != nil {
+				last, ok := cm.LastTheme()
+				if !ok {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Fprintf(os.Stderr, "Warning: %v; saving the last applied color\n", err)
+				theme = last
+			}
+		case "last":
+			var ok bool
+			if theme, ok = cm.LastTheme(); !ok {
+				fmt.Fprintln(os.Stderr, "Error: no color has been applied yet")
+				os.Exit(1)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown source %q (expected terminal or last)\n", from)
+			os.Exit(1)
+		}
+
+		theme.Name = args[0]
+		if err := cm.SaveTheme(theme); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving theme: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("💾 Saved theme %s %s\n", theme.Name, themeSwatches(theme))
+	},
+}
+
+var themeApplyCmd = &cobra.Command{
+	Use:   "apply <name>",
+	Short: "Apply a saved theme",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cm := newColorManager()
+		theme, err := cm.LoadTheme(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := cm.ApplyTheme(theme); err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying theme: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("🎨 Applied theme %s %s\n", theme.Name, themeSwatches(theme))
+	},
+}
+
+var themeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved themes with swatches",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cm := newColorManager()
+		themes, err := cm.ListThemes()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(themes) == 0 {
+			fmt.Println("No saved themes")
+			return
+		}
+		for _, theme := range themes {
+			fmt.Printf("%-20s %s\n", theme.Name, themeSwatches(theme))
+		}
+	},
+}
+
+var themeShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show the colors of a saved theme",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cm := newColorManager()
+		theme, err := cm.LoadTheme(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("🎨 Theme %s (saved %s)\n", theme.Name, theme.Created.Format("2006-01-02 15:04"))
+		fmt.Printf("  %-16s %s %s\n", "background", swatch(theme.Background.To8()), theme.Background.Hex())
+		fmt.Printf("  %-16s %s %s\n", "foreground", swatch(theme.Foreground.To8()), theme.Foreground.Hex())
+		for i, color := range theme.Palette {
+			fmt.Printf("  %-16s %s %s\n", fmt.Sprintf("color%d", i), swatch(color.To8()), color.Hex())
+		}
+		optional := []struct {
+			name  string
+			color *internal.RGB16
+		}{
+			{"cursor", theme.Cursor},
+			{"cursor text", theme.CursorText},
+			{"selection", theme.Selection},
+			{"selection text", theme.SelectionText},
+		}
+		for _, o := range optional {
+			if o.color != nil {
+				fmt.Printf("  %-16s %s %s\n", o.name, swatch(o.color.To8()), o.color.Hex())
+			}
+		}
+	},
+}
+
+var themeDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a saved theme",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cm := newColorManager()
+		deleted, err := cm.DeleteTheme(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !deleted {
+			fmt.Printf("No theme named %s\n", args[0])
+			return
+		}
+		fmt.Printf("🗑️ Deleted theme %s\n", args[0])
+	},
+}
+
+var themeImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a theme from another terminal's scheme file",
+	Long: `Import themes from iTerm2 .itermcolors, base16/base24 YAML,
+Alacritty TOML, Windows Terminal JSON or Xresources files.
+
+The format is detected from the file name and content unless --format
+is given. The file is validated before anything is saved: every ANSI
+color, the background and the foreground must be present. Keys the
+theme model has no place for are reported.
+
+A Windows Terminal settings.json imports every scheme it lists. Themes
+are named after the scheme, or the file when it has no name; --name
+overrides it for single-theme files.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		name, _ := cmd.Flags().GetString("name")
+		force, _ := cmd.Flags().GetBool("force")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if name != "" {
+			if err := internal.ValidateThemeName(name); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		results, err := internal.ImportThemes(args[0], data, format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Invalid theme file:\n%v\n", err)
+			if len(results) == 0 {
+				os.Exit(1)
+			}
+		}
+		if name != "" {
+			if len(results) != 1 {
+				fmt.Fprintln(os.Stderr, "Error: --name needs a file with exactly one scheme")
+				os.Exit(1)
+			}
+			results[0].Theme.Name = name
+		}
+
+		cm := newColorManager()
+		failed := err != nil
+		for _, result := range results {
+			theme := result.Theme
+			if name == "" {
+				theme.Name = themeNameFrom(theme.Name)
+			}
+
+			if !dryRun && !force {
+				if _, err := cm.LoadTheme(theme.Name); err == nil {
+					fmt.Fprintf(os.Stderr, "⚠️ Theme %s already exists (use --force to replace it)\n", theme.Name)
+					failed = true
+					continue
+				}
+			}
+
+			if !dryRun {
+				if err := cm.SaveTheme(theme); err != nil {
+					fmt.Fprintf(os.Stderr, "Error saving theme %s: %v\n", theme.Name, err)
+					failed = true
+					continue
+				}
+			}
+
+			verb := "Imported"
+			if dryRun {
+				verb = "Validated"
+			}
+			fmt.Printf("📥 %s %s theme %s %s\n", verb, result.Format, theme.Name, themeSwatches(theme))
+			if len(result.Unsupported) > 0 {
+				fmt.Printf("   Unsupported keys: %s\n", strings.Join(result.Unsupported, ", "))
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// themeNameFrom turns a scheme name such as "Solarized Dark" into a theme
+// name such as "solarized-dark"
+func themeNameFrom(name string) string {
+	if internal.ValidateThemeName(name) == nil {
+		return name
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '_':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// themeSwatches renders a theme's background and palette as swatches
+func themeSwatches(theme internal.Theme) string {
+	parts := []string{swatch(theme.Background.To8())}
+	for _, color := range theme.Palette {
+		c := color.To8()
+		parts = append(parts, fmt.Sprintf("\x1b[48;2;%d;%d;%dm  \x1b[0m", c.R, c.G, c.B))
+	}
+	return strings.Join(parts, "")
+}
+
+var themeExportCmd = &cobra.Command{
+	Use:   "export [name]",
+	Short: "Export a theme to another terminal's format",
+	Long: `Export a named theme, or the colors of a directory with --dir, to
+iTerm2 .itermcolors, kitty, Alacritty TOML, WezTerm Lua, foot ini,
+Xresources, Windows Terminal JSON or base16 YAML.
+
+A directory is exported with the theme its .colorrc or rule names, or
+else its generated color, the matching foreground and the default xterm
+palette. The result is written to stdout unless --output is given.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		dir, _ := cmd.Flags().GetString("dir")
+
+		if (len(args) == 1) == (dir != "") {
+			fmt.Fprintln(os.Stderr, "Error: give either a theme name or --dir")
+			os.Exit(1)
+		}
+
+		cm := newColorManager()
+		var theme internal.Theme
+		if dir != "" {
+			path := internal.CanonicalPath(dir)
+			theme = cm.DirectoryThemeFor(path)
+			if theme.Name == "" {
+				theme.Name = themeNameFrom(filepath.Base(path))
+			}
+		} else {
+			var err error
+			if theme, err = cm.LoadTheme(args[0]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		data, err := internal.ExportTheme(theme, format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if output == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("📤 Exported theme %s as %s to %s\n", theme.Name, format, output)
+	},
+}
+
+func init() {
+	themeSaveCmd.Flags().String("from", "terminal", "Where to capture colors from: terminal or last")
+	themeCmd.AddCommand(themeSaveCmd)
+	themeCmd.AddCommand(themeApplyCmd)
+	themeCmd.AddCommand(themeListCmd)
+	themeCmd.AddCommand(themeShowCmd)
+	themeCmd.AddCommand(themeDeleteCmd)
+	themeImportCmd.Flags().String("format", "", "Scheme format: "+strings.Join(internal.ThemeFormats, ", "))
+	themeImportCmd.Flags().String("name", "", "Name of the imported theme")
+	themeImportCmd.Flags().Bool("force", false, "Replace existing themes")
+	themeImportCmd.Flags().Bool("dry-run", false, "Validate the file without saving")
+	themeCmd.AddCommand(themeImportCmd)
+	themeExportCmd.Flags().String("format", "", "Export format: "+strings.Join(internal.ExportFormats, ", "))
+	themeExportCmd.Flags().String("output", "", "File to write instead of stdout")
+	themeExportCmd.Flags().String("dir", "", "Export the colors of this directory instead of a named theme")
+	themeCmd.AddCommand(themeExportCmd)
+	rootCmd.AddCommand(themeCmd)
+}
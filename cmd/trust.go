@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"color/internal"
+
+	"github.com/spf13/cobra"
+)
+
+var trustCmd = &cobra.Command{
+	Use:   "trust [path]",
+	Short: "Trust a .colorrc file",
+	Long: `Approve a .colorrc file so directory colors use its settings.
+
+Trust is bound to the file's content: after any change the file is
+skipped until it is trusted again. The path may be a .colorrc file or
+a directory containing one; it defaults to the current directory.
+
+Directories matching a glob in the config's [colorrc] allow list are
+trusted without approval.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := colorrcPath(args)
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if _, err := internal.ParseColorrc(file, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: refusing to trust invalid file:\n%v\n", err)
+			os.Exit(1)
+		}
+
+		if err := internal.LoadTrustStore().Trust(file, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving trust: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("🔓 Trusted %s\n", file)
+	},
+}
+
+var untrustCmd = &cobra.Command{
+	Use:   "untrust [path]",
+	Short: "Revoke trust in a .colorrc file",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := colorrcPath(args)
+
+		removed, err := internal.LoadTrustStore().Untrust(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving trust: %v\n", err)
+			os.Exit(1)
+		}
+
+		if removed {
+			fmt.Printf("🔒 Revoked trust in %s\n", file)
+		} else {
+			fmt.Printf("%s was not trusted\n", file)
+		}
+	},
+}
+
+// colorrcPath resolves a .colorrc file or directory argument to the path
+// the .colorrc lookup trusts it under: the file in its canonical directory
+func colorrcPath(args []string) string {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(internal.CanonicalPath(path), internal.ColorrcName)
+	}
+	return filepath.Join(internal.CanonicalPath(filepath.Dir(path)), filepath.Base(path))
+}
+
+func init() {
+	rootCmd.AddCommand(trustCmd)
+	rootCmd.AddCommand(untrustCmd)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 	"syscall"
 
 	"color/internal"
@@ -41,11 +42,12 @@ Example:
 
 // wrapCommand implements command wrapping with color management
 func wrapCommand(args []string) error {
-	cm := internal.NewColorManager()
+	cm := newColorManager()
 	
 	// Set Claude session colors
 	claudeColor := cm.GenerateClaudeTheme()
-	if err := cm.SetITermColor(claudeColor); err != nil {
+	wrapped := strings.Join(args, " ")
+	if err := cm.ApplyColor(claudeColor, internal.ColorChange{Source: "claude", Command: wrapped}); err != nil {
 		return fmt.Errorf("failed to set Claude theme: %w", err)
 	}
 	
@@ -64,7 +66,7 @@ func wrapCommand(args []string) error {
 	cwd, cwdErr := os.Getwd()
 	if cwdErr == nil {
 		dirColor := cm.GenerateDirectoryTheme(cwd)
-		if restoreErr := cm.SetITermColor(dirColor); restoreErr != nil {
+		if restoreErr := cm.ApplyColor(dirColor, internal.ColorChange{Source: "directory", Path: cwd, Command: wrapped}); restoreErr != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to restore directory colors: %v\n", restoreErr)
 		}
 	}
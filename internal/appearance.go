@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Appearance selects whether generated colors target dark or light terminals
+type Appearance string
+
+const (
+	AppearanceDark  Appearance = "dark"
+	AppearanceLight Appearance = "light"
+)
+
+// ParseAppearance parses an appearance name ("dark" or "light")
+func ParseAppearance(name string) (Appearance, error) {
+	switch Appearance(strings.ToLower(strings.TrimSpace(name))) {
+	case "", AppearanceDark:
+		return AppearanceDark, nil
+	case AppearanceLight:
+		return AppearanceLight, nil
+	}
+	return "", fmt.Errorf("unknown appearance %q (expected dark or light)", name)
+}
+
+// SetAppearance changes the appearance used by all generators
+func (c *ColorManager) SetAppearance(appearance Appearance) {
+	c.appearance = appearance
+}
+
+// Appearance returns the active appearance
+func (c *ColorManager) Appearance() Appearance {
+	if c.appearance == "" {
+		return AppearanceDark
+	}
+	return c.appearance
+}
+
+// ResetColor returns the default background for the active appearance
+func (c *ColorManager) ResetColor() RGB {
+	if c.Appearance() == AppearanceLight {
+		return c.config.Reset.Light
+	}
+	return c.config.Reset.Dark
+}
+
+// ForegroundFor returns a readable text color for the given background
+func (c *ColorManager) ForegroundFor(background RGB) RGB {
+	if relativeLuminance(background) > 0.4 {
+		return c.config.Foreground.Light
+	}
+	return c.config.Foreground.Dark
+}
+
+// toAppearance maps a color generated in the canonical dark range into the
+// active appearance, keeping its hue
+func (c *ColorManager) toAppearance(rgb RGB) RGB {
+	return c.toAppearance16(rgb.To16()).To8()
+}
+
+// toAppearance16 is toAppearance at full precision. Light colors mirror the
+// dark range: distance from black becomes distance from white, and saturation
+// is scaled down so backgrounds stay pastel.
+func (c *ColorManager) toAppearance16(rgb RGB16) RGB16 {
+	if c.Appearance() != AppearanceLight {
+		return rgb
+	}
+	light := c.config.Light
+	hsv := c.RGB16ToHSV(rgb)
+	return c.HSVToRGB16(hsv.H, hsv.S*light.SaturationScale, 1-hsv.V*light.ValueScale)
+}
+
+// fromAppearance is the inverse of toAppearance, mapping a color of the active
+// appearance back into the canonical dark range
+func (c *ColorManager) fromAppearance(rgb RGB) RGB {
+	return c.fromAppearance16(rgb.To16()).To8()
+}
+
+// fromAppearance16 is fromAppearance at full precision
+func (c *ColorManager) fromAppearance16(rgb RGB16) RGB16 {
+	if c.Appearance() != AppearanceLight {
+		return rgb
+	}
+	light := c.config.Light
+	hsv := c.RGB16ToHSV(rgb)
+	return c.HSVToRGB16(hsv.H, hsv.S/light.SaturationScale, (1-hsv.V)/light.ValueScale)
+}
+
+// relativeLuminance returns the WCAG relative luminance of a color (0-1)
+func relativeLuminance(rgb RGB) float64 {
+	channel := func(v uint8) float64 {
+		c := float64(v) / 255.0
+		if c <= 0.03928 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(rgb.R) + 0.7152*channel(rgb.G) + 0.0722*channel(rgb.B)
+}
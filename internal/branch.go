@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GitState describes the checked-out branch and any operation in progress
+type GitState struct {
+	Branch    string // Branch name; during a rebase, the branch being rebased
+	Detached  bool   // HEAD points at a commit rather than a branch
+	Operation string // "rebase", "merge", "cherry-pick", "revert", "bisect" or ""
+}
+
+// ReadState reads HEAD and the operation markers from the git directory
+func (r *GitRepo) ReadState() GitState {
+	var state GitState
+
+	if head, err := os.ReadFile(filepath.Join(r.GitDir, "HEAD")); err == nil {
+		ref, isRef := strings.CutPrefix(strings.TrimSpace(string(head)), "ref:")
+		if isRef {
+			state.Branch = strings.TrimPrefix(strings.TrimSpace(ref), "refs/heads/")
+		} else {
+			state.Detached = true
+		}
+	}
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(r.GitDir, name))
+		return err == nil
+	}
+
+	switch {
+	case exists("rebase-merge") || exists("rebase-apply"):
+		state.Operation = "rebase"
+		// HEAD is detached while rebasing; head-name holds the branch
+		for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+			if name, err := os.ReadFile(filepath.Join(r.GitDir, dir, "head-name")); err == nil {
+				state.Branch = strings.TrimPrefix(strings.TrimSpace(string(name)), "refs/heads/")
+			}
+		}
+	case exists("MERGE_HEAD"):
+		state.Operation = "merge"
+	case exists("CHERRY_PICK_HEAD"):
+		state.Operation = "cherry-pick"
+	case exists("REVERT_HEAD"):
+		state.Operation = "revert"
+	case exists("BISECT_LOG"):
+		state.Operation = "bisect"
+	}
+
+	return state
+}
+
+// BranchTint returns the repository state of a directory and a description
+// of the tint it receives; the description is empty when no tint applies
+func (c *ColorManager) BranchTint(directoryPath string) (GitState, string) {
+	branch := c.config.Branch
+	if !branch.Enabled {
+		return GitState{}, ""
+	}
+	repo, ok := FindGitRepo(directoryPath)
+	if !ok {
+		return GitState{}, ""
+	}
+
+	state := repo.ReadState()
+	switch {
+	case state.Operation == "rebase":
+		return state, "rebase in progress"
+	case state.Operation != "":
+		return state, state.Operation + " in progress"
+	case state.Detached:
+		return state, "detached HEAD"
+	case c.isProtectedBranch(state.Branch):
+		return state, "protected branch " + state.Branch
+	case state.Branch != "" && branch.FeatureHueOffset != 0:
+		return state, "feature branch " + state.Branch
+	}
+	return state, ""
+}
+
+// applyBranchTint tints a directory color according to its git state
+func (c *ColorManager) applyBranchTint(directoryPath string, color RGB16) RGB16 {
+	state, reason := c.BranchTint(directoryPath)
+	if reason == "" {
+		return color
+	}
+
+	branch := c.config.Branch
+	switch {
+	case state.Operation == "rebase":
+		return blendTint(color, branch.RebaseTint)
+	case state.Operation != "":
+		return blendTint(color, branch.MergeTint)
+	case state.Detached:
+		return blendTint(color, branch.DetachedTint)
+	case c.isProtectedBranch(state.Branch):
+		return blendTint(color, branch.ProtectedTint)
+	}
+
+	// Feature branches get a subtle hue offset derived from their name
+	sum := md5.Sum([]byte(state.Branch))
+	position := float64(binary.BigEndian.Uint32(sum[:4]))/float64(0xFFFFFFFF)*2 - 1 // -1 to +1
+	hsv := c.RGB16ToHSV(color)
+	return c.HSVToRGB16(hsv.H+position*branch.FeatureHueOffset, hsv.S, hsv.V)
+}
+
+// isProtectedBranch reports whether branch matches a protected pattern
+func (c *ColorManager) isProtectedBranch(branch string) bool {
+	if branch == "" {
+		return false
+	}
+	for _, pattern := range c.config.Branch.Protected {
+		if MatchGlob(pattern, branch) {
+			return true
+		}
+	}
+	return false
+}
+
+// blendTint mixes a tint into a color in OKLab space
+func blendTint(color RGB16, tint Tint) RGB16 {
+	if tint.Strength == 0 {
+		return color
+	}
+	return LerpOKLab16(color, tint.Color.To16(), tint.Strength)
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"crypto/md5"
 	"fmt"
+	"log"
 	"math"
 	"math/rand"
 	"os"
@@ -17,6 +18,24 @@ type RGB struct {
 	R, G, B uint8
 }
 
+// RGB16 represents high-precision RGB color values (0-65535), matching the
+// 16-bit channels carried by iTerm2. Color math is done at this precision and
+// only rounded to RGB for display.
+type RGB16 struct {
+	R, G, B uint16
+}
+
+// To16 widens an RGB color to 16-bit channels without loss
+func (rgb RGB) To16() RGB16 {
+	return RGB16{R: uint16(rgb.R) * 257, G: uint16(rgb.G) * 257, B: uint16(rgb.B) * 257}
+}
+
+// To8 rounds a 16-bit color to the nearest RGB color
+func (rgb RGB16) To8() RGB {
+	round := func(v uint16) uint8 { return uint8((uint32(v) + 128) / 257) }
+	return RGB{R: round(rgb.R), G: round(rgb.G), B: round(rgb.B)}
+}
+
 // HSV represents HSV color values
 type HSV struct {
 	H, S, V float64
@@ -25,17 +44,40 @@ type HSV struct {
 // ColorManager handles terminal color operations
 type ColorManager struct {
 	rng         *rand.Rand
-	persistence *PersistenceManager
+	seed        int64
+	seeded      bool
+	randomUsed  bool
+	store       Store
+	config      *Config
+	appearance  Appearance
+	transition  TransitionOptions
+	trustPrompt func(path string, data []byte) bool
+
+	settingsCache map[string]DirectorySettings
 }
 
-// NewColorManager creates a new color manager
-func NewColorManager() *ColorManager {
+// NewColorManager creates a new color manager reading its tunables from cfg,
+// or from the defaults when cfg is nil
+func NewColorManager(cfg *Config) *ColorManager {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	seed := newSeed()
 	return &ColorManager{
-		rng:         rand.New(rand.NewSource(time.Now().UnixNano())),
-		persistence: NewPersistenceManager(),
+		rng:        rand.New(rand.NewSource(seed)),
+		seed:       seed,
+		store:      NewStore(cfg.Persistence),
+		config:     cfg,
+		appearance: cfg.Appearance,
+		transition: cfg.Transition,
 	}
 }
 
+// Config returns the configuration the manager was created with
+func (c *ColorManager) Config() *Config {
+	return c.config
+}
+
 // RGBToITerm converts RGB (0-255) to iTerm2 color values (0-65535)
 func (c *ColorManager) RGBToITerm(r, g, b uint8) (uint16, uint16, uint16) {
 	return uint16(r) * 257, uint16(g) * 257, uint16(b) * 257
@@ -43,6 +85,11 @@ func (c *ColorManager) RGBToITerm(r, g, b uint8) (uint16, uint16, uint16) {
 
 // HSVToRGB converts HSV to RGB
 func (c *ColorManager) HSVToRGB(h, s, v float64) RGB {
+	return c.HSVToRGB16(h, s, v).To8()
+}
+
+// HSVToRGB16 converts HSV to 16-bit RGB, rounding to the nearest value
+func (c *ColorManager) HSVToRGB16(h, s, v float64) RGB16 {
 	// Ensure h is in [0, 1)
 	for h >= 1.0 {
 		h -= 1.0
@@ -76,18 +123,23 @@ func (c *ColorManager) HSVToRGB(h, s, v float64) RGB {
 		rPrime, gPrime, bPrime = chroma, 0, x
 	}
 
-	return RGB{
-		R: uint8((rPrime + m) * 255),
-		G: uint8((gPrime + m) * 255),
-		B: uint8((bPrime + m) * 255),
+	return RGB16{
+		R: toChannel16(rPrime + m),
+		G: toChannel16(gPrime + m),
+		B: toChannel16(bPrime + m),
 	}
 }
 
 // RGBToHSV converts RGB to HSV
 func (c *ColorManager) RGBToHSV(rgb RGB) HSV {
-	r := float64(rgb.R) / 255.0
-	g := float64(rgb.G) / 255.0
-	b := float64(rgb.B) / 255.0
+	return c.RGB16ToHSV(rgb.To16())
+}
+
+// RGB16ToHSV converts 16-bit RGB to HSV
+func (c *ColorManager) RGB16ToHSV(rgb RGB16) HSV {
+	r := float64(rgb.R) / 65535.0
+	g := float64(rgb.G) / 65535.0
+	b := float64(rgb.B) / 65535.0
 
 	max := math.Max(r, math.Max(g, b))
 	min := math.Min(r, math.Min(g, b))
@@ -130,6 +182,21 @@ func (c *ColorManager) RGBToHSV(rgb RGB) HSV {
 
 // GetCurrentColor gets current background color from iTerm2
 func (c *ColorManager) GetCurrentColor() (RGB, error) {
+	color, err := c.GetCurrentColor16()
+	return color.To8(), err
+}
+
+// GetCurrentColor16 gets current background color from iTerm2 at full
+// precision. In an SSH session iTerm2 can't be asked, so the last color
+// applied there stands in.
+func (c *ColorManager) GetCurrentColor16() (RGB16, error) {
+	if remoteSession() {
+		if color, ok := c.store.GetLastColor(); ok {
+			return color, nil
+		}
+		return c.ResetColor().To16(), nil
+	}
+
 	script := `
 	tell application "iTerm2"
 		tell current session of current tab of current window
@@ -141,45 +208,55 @@ func (c *ColorManager) GetCurrentColor() (RGB, error) {
 	cmd := exec.Command("osascript", "-e", script)
 	output, err := cmd.Output()
 	if err != nil {
-		// Return default dark gray
-		return RGB{30, 30, 30}, nil
+		// Return the default background for the active appearance
+		return c.ResetColor().To16(), nil
 	}
 
 	// Parse output like "0, 0, 0"
 	colorStr := strings.TrimSpace(string(output))
 	parts := strings.Split(colorStr, ",")
 	if len(parts) != 3 {
-		return RGB{30, 30, 30}, nil
+		return c.ResetColor().To16(), nil
 	}
 
 	var values [3]uint16
 	for i, part := range parts {
-		val, err := strconv.Atoi(strings.TrimSpace(part))
+		val, err := strconv.ParseUint(strings.TrimSpace(part), 10, 16)
 		if err != nil {
-			return RGB{30, 30, 30}, nil
+			return c.ResetColor().To16(), nil
 		}
 		values[i] = uint16(val)
 	}
 
-	// Convert from iTerm2 values (0-65535) to RGB (0-255)
-	return RGB{
-		R: uint8(values[0] / 257),
-		G: uint8(values[1] / 257),
-		B: uint8(values[2] / 257),
-	}, nil
+	// iTerm2 values (0-65535) are kept as-is
+	return RGB16{R: values[0], G: values[1], B: values[2]}, nil
 }
 
-// SetITermColor sets iTerm2 background color
+// SetITermColor sets iTerm2 background color and a matching foreground
 func (c *ColorManager) SetITermColor(rgb RGB) error {
-	iR, iG, iB := c.RGBToITerm(rgb.R, rgb.G, rgb.B)
+	return c.SetITermColor16(rgb.To16())
+}
+
+// SetITermColor16 sets iTerm2 background color at full precision. In an
+// SSH session the colors are sent to the terminal as OSC 11 and OSC 10
+// escape sequences.
+func (c *ColorManager) SetITermColor16(rgb RGB16) error {
+	iR, iG, iB := rgb.R, rgb.G, rgb.B
+	fg := c.ForegroundFor(rgb.To8())
+	fR, fG, fB := c.RGBToITerm(fg.R, fg.G, fg.B)
+
+	if remoteSession() {
+		return writeOSC(oscColor("11", rgb), oscColor("10", RGB16{R: fR, G: fG, B: fB}))
+	}
 
 	script := fmt.Sprintf(`
 	tell application "iTerm2"
 		tell current session of current tab of current window
 			set background color to {%d, %d, %d}
+			set foreground color to {%d, %d, %d}
 		end tell
 	end tell
-	`, iR, iG, iB)
+	`, iR, iG, iB, fR, fG, fB)
 
 	cmd := exec.Command("osascript", "-e", script)
 	return cmd.Run()
@@ -187,31 +264,35 @@ func (c *ColorManager) SetITermColor(rgb RGB) error {
 
 // GenerateClaudeTheme generates Claude-specific color theme
 func (c *ColorManager) GenerateClaudeTheme() RGB {
-	// Check if we have a recent Claude color stored
-	if c.persistence != nil && c.persistence.IsEnabled() {
-		if color, found := c.persistence.GetLastClaudeColor(); found {
-			return color
+	// Check if we have a recent Claude color stored, unless a seed was given
+	if !c.seeded {
+		if color, found := c.store.GetLastClaudeColor(); found {
+			return c.applyScheduleAt(c.toAppearance16(color), time.Now()).To8()
 		}
 	}
 
 	// Generate new Claude color
-	baseHues := []float64{0.6, 0.75, 0.85} // Blue to purple range
-	hue := baseHues[c.rng.Intn(len(baseHues))]
-	saturation := 0.4 + c.rng.Float64()*0.4 // 0.4-0.8 (more saturated)
-	value := 0.25 + c.rng.Float64()*0.15    // 0.25-0.4 (brighter for visibility)
+	claude := c.config.Claude
+	hue := claude.Hues[c.randIntn(len(claude.Hues))]
+	saturation := claude.Saturation.At(c.randFloat())
+	value := claude.Value.At(c.randFloat())
 
-	color := c.HSVToRGB(hue, saturation, value)
+	color := c.HSVToRGB16(hue, saturation, value)
 
 	// Store the new color
-	if c.persistence != nil && c.persistence.IsEnabled() {
-		c.persistence.SetLastClaudeColor(color)
-	}
+	c.store.SetLastClaudeColor(color)
 
-	return color
+	return c.applyScheduleAt(c.toAppearance16(color), time.Now()).To8()
 }
 
 // GenerateDirectoryTheme generates consistent color for directory based on path hash
 func (c *ColorManager) GenerateDirectoryTheme(directoryPath string) RGB {
+	return c.DirectoryThemeAt(directoryPath, time.Now())
+}
+
+// DirectoryThemeAt returns the directory color as it looks at time t, with
+// the schedule window covering t applied
+func (c *ColorManager) DirectoryThemeAt(directoryPath string, t time.Time) RGB {
 	if directoryPath == "" {
 		var err error
 		directoryPath, err = os.Getwd()
@@ -219,14 +300,125 @@ func (c *ColorManager) GenerateDirectoryTheme(directoryPath string) RGB {
 			directoryPath = "/tmp"
 		}
 	}
+	directoryPath = CanonicalPath(directoryPath)
+
+	// An active danger zone, such as a production kube context, overrides
+	// every directory
+	if color, ok := c.dangerColor(); ok {
+		return color
+	}
+
+	color := c.directoryColor(directoryPath)
+
+	// In an SSH session the remote host's color replaces or blends with it
+	if host, ok := RemoteHost(); ok && c.config.SSH.Enabled {
+		color = LerpOKLab16(color, c.HostColor(host), c.config.SSH.Blend)
+	}
 
-	// Check if we have this directory color stored
-	if c.persistence != nil && c.persistence.IsEnabled() {
-		if color, found := c.persistence.GetDirectoryColor(directoryPath); found {
-			return color
+	return c.applyScheduleAt(color, t).To8()
+}
+
+// directoryColor returns the color of a directory in the active appearance
+func (c *ColorManager) directoryColor(directoryPath string) RGB16 {
+	// A pinned color overrides everything else
+	if pin, ok := c.PinFor(directoryPath); ok {
+		return c.applyBranchTint(directoryPath, c.toAppearance16(pin.Entry.Color16()))
+	}
+
+	// A .colorrc or rule for the directory overrides the generator
+	settings := c.DirectorySettings(directoryPath)
+	if settings.Theme != nil {
+		theme, err := c.LoadTheme(*settings.Theme)
+		if err == nil {
+			return c.applyBranchTint(directoryPath, theme.Background)
+		}
+		log.Printf("Ignoring theme for %s: %v", directoryPath, err)
+	}
+	if settings.Color != nil {
+		if c.Appearance() == AppearanceLight && settings.LightColor != nil {
+			return c.applyBranchTint(directoryPath, settings.LightColor.To16())
 		}
+		return c.applyBranchTint(directoryPath, c.toAppearance16(settings.Color.To16()))
 	}
 
+	// The branch tint follows the checkout, so it is applied after caching
+	color := c.toAppearance16(c.generatedColor(directoryPath, settings))
+	return c.applyBranchTint(directoryPath, color)
+}
+
+// generatedColor returns the stored or generated color of a directory in
+// the canonical dark space
+func (c *ColorManager) generatedColor(directoryPath string, settings DirectorySettings) RGB16 {
+	key, depth, names := c.colorKey(directoryPath)
+
+	// Check if we have this directory color stored. Colors derived from a
+	// .colorrc or rule aren't cached so edits take effect immediately.
+	color, found := RGB16{}, false
+	if settings.IsEmpty() {
+		color, found = c.store.GetDirectoryColor(key)
+	}
+
+	if !found {
+		color = c.hashDirectoryColor(key, settings)
+
+		// Store the new directory color
+		if settings.IsEmpty() {
+			c.store.SetDirectoryColor(key, color, "directory")
+		}
+	}
+
+	if len(names) > 0 {
+		color = c.deriveHierarchy(color, names, settings)
+	}
+
+	return c.offsetDepth(color, depth)
+}
+
+// colorKey returns the key a directory's color is stored under, with the
+// depth offset and the names to derive it by below that key.
+func (c *ColorManager) colorKey(directoryPath string) (string, int, []string) {
+	// Inside a git repository the whole project shares the root's color.
+	// The hierarchical strategy instead starts at an anchor directory and
+	// derives each level below it from its parent.
+	key, depth := c.ProjectKey(directoryPath)
+	var names []string
+	if c.config.Strategy == StrategyHierarchical {
+		key, names = c.HierarchyAnchor(directoryPath)
+		depth = 0
+	}
+	return c.pathKey(key), depth, names
+}
+
+// ProjectKey returns the path a directory's color is derived from, and how
+// deep the directory is below it. Inside a git repository this is the
+// project root; elsewhere it is the directory itself.
+func (c *ColorManager) ProjectKey(directoryPath string) (string, int) {
+	if !c.config.Git.Enabled {
+		return directoryPath, 0
+	}
+	repo, ok := FindGitRepo(directoryPath)
+	if !ok {
+		return directoryPath, 0
+	}
+	return repo.ProjectRoot(), repo.Depth(directoryPath)
+}
+
+// offsetDepth applies the configured lightness offset for subdirectories
+func (c *ColorManager) offsetDepth(color RGB16, depth int) RGB16 {
+	git := c.config.Git
+	if depth == 0 || git.SubdirLightness == 0 {
+		return color
+	}
+	if depth > git.MaxDepth {
+		depth = git.MaxDepth
+	}
+	hsv := c.RGB16ToHSV(color)
+	return c.HSVToRGB16(hsv.H, hsv.S, hsv.V+float64(depth)*git.SubdirLightness)
+}
+
+// hashDirectoryColor derives a directory color from its path hash, using the
+// palette or generator ranges from settings when declared
+func (c *ColorManager) hashDirectoryColor(directoryPath string, settings DirectorySettings) RGB16 {
 	// Generate new directory color based on path hash
 	hash := md5.Sum([]byte(directoryPath))
 	hashStr := fmt.Sprintf("%x", hash)
@@ -236,41 +428,54 @@ func (c *ColorManager) GenerateDirectoryTheme(directoryPath string) RGB {
 	hueInt, _ := strconv.ParseUint(hueHex, 16, 64)
 	hue := float64(hueInt) / float64(0xFFFFFFFF)
 
+	if len(settings.Palette) > 0 {
+		return settings.Palette[hueInt%uint64(len(settings.Palette))].To16()
+	}
+
+	generator := c.config.Directory
+	if settings.Saturation != nil {
+		generator.Saturation = *settings.Saturation
+	}
+	if settings.Value != nil {
+		generator.Value = *settings.Value
+	}
+
 	// Use consistent saturation and value for better visibility
 	satHex := hashStr[8:10]
 	satInt, _ := strconv.ParseUint(satHex, 16, 8)
-	saturation := 0.5 + (float64(satInt)/255.0)*0.3 // 0.5-0.8 (more saturated)
+	saturation := generator.Saturation.At(float64(satInt) / 255.0)
 
 	valHex := hashStr[10:12]
 	valInt, _ := strconv.ParseUint(valHex, 16, 8)
-	value := 0.25 + (float64(valInt)/255.0)*0.2 // 0.25-0.45 (brighter)
+	value := generator.Value.At(float64(valInt) / 255.0)
 
-	color := c.HSVToRGB(hue, saturation, value)
-
-	// Store the new directory color
-	if c.persistence != nil && c.persistence.IsEnabled() {
-		c.persistence.SetDirectoryColor(directoryPath, color)
-	}
-
-	return color
+	return c.HSVToRGB16(hue, saturation, value)
 }
 
 // GenerateVariant generates color variant based on current color
 func (c *ColorManager) GenerateVariant(baseColor RGB, mode string) RGB {
-	hsv := c.RGBToHSV(baseColor)
+	return c.GenerateVariant16(baseColor.To16(), mode).To8()
+}
+
+// GenerateVariant16 generates a color variant at full precision, so repeated
+// cycling doesn't accumulate rounding drift
+func (c *ColorManager) GenerateVariant16(baseColor RGB16, mode string) RGB16 {
+	// Variants are computed in the canonical dark range and mapped back
+	hsv := c.RGB16ToHSV(c.fromAppearance16(baseColor))
+	cycle := c.config.Cycle
 
 	switch mode {
 	case "hue_shift":
-		hsv.H += 0.15 + c.rng.Float64()*0.2 // +0.15 to +0.35 (more dramatic)
+		hsv.H += cycle.HueShift.At(c.randFloat())
 		if hsv.H >= 1.0 {
 			hsv.H -= 1.0
 		}
 	case "brightness":
-		change := -0.4 + c.rng.Float64()*0.8 // -0.4 to +0.4 (more dramatic)
-		hsv.V = math.Max(0.2, math.Min(0.8, hsv.V+change))
+		change := cycle.Step * (c.randFloat()*2 - 1) // -step to +step
+		hsv.V = cycle.Value.Clamp(hsv.V + change)
 	case "saturation":
-		change := -0.4 + c.rng.Float64()*0.8 // -0.4 to +0.4 (more dramatic)
-		hsv.S = math.Max(0.2, math.Min(0.9, hsv.S+change))
+		change := cycle.Step * (c.randFloat()*2 - 1) // -step to +step
+		hsv.S = cycle.Saturation.Clamp(hsv.S + change)
 	case "complement":
 		hsv.H += 0.5
 		if hsv.H >= 1.0 {
@@ -280,42 +485,41 @@ func (c *ColorManager) GenerateVariant(baseColor RGB, mode string) RGB {
 		hsv.S = math.Min(0.9, hsv.S+0.2)
 		hsv.V = math.Min(0.7, hsv.V+0.1)
 	default:
+		if IsHarmonyMode(mode) {
+			// Without remembered state, step to the first harmony partner
+			return c.HarmonySet16(baseColor, mode)[1]
+		}
+
 		// Random mode selection if mode is unknown
 		modes := []string{"hue_shift", "brightness", "saturation", "complement"}
-		return c.GenerateVariant(baseColor, modes[c.rng.Intn(len(modes))])
+		return c.GenerateVariant16(baseColor, modes[c.randIntn(len(modes))])
 	}
 
-	return c.HSVToRGB(hsv.H, hsv.S, hsv.V)
+	return c.toAppearance16(c.HSVToRGB16(hsv.H, hsv.S, hsv.V))
+}
+
+// SetStore replaces the store colors persist in, closing the previous one
+func (c *ColorManager) SetStore(store Store) {
+	c.store.Close()
+	c.store = store
 }
 
 // GetPersistenceStatus returns the status of the persistence system
 func (c *ColorManager) GetPersistenceStatus() string {
-	if c.persistence == nil {
-		return "❌ Persistence system not initialized"
-	}
-	return c.persistence.GetConnectionStatus()
+	return c.store.GetConnectionStatus()
 }
 
-// GetColorHistory returns recent color history
-func (c *ColorManager) GetColorHistory(limit int) ([]ColorEntry, error) {
-	if c.persistence == nil {
-		return []ColorEntry{}, nil
-	}
-	return c.persistence.GetColorHistory(limit)
+// CountDirectoryColors returns how many directory colors are stored
+func (c *ColorManager) CountDirectoryColors() (int, error) {
+	return c.store.CountDirectoryColors()
 }
 
-// ClearColorCache clears all stored colors
-func (c *ColorManager) ClearColorCache() error {
-	if c.persistence == nil {
-		return nil
-	}
-	return c.persistence.ClearColorCache()
+// ClearColorCache clears all stored colors, including pins when all is set
+func (c *ColorManager) ClearColorCache(all bool) error {
+	return c.store.ClearColorCache(all)
 }
 
 // Close closes persistence connections
 func (c *ColorManager) Close() error {
-	if c.persistence != nil {
-		return c.persistence.Close()
-	}
-	return nil
-}
\ No newline at end of file
+	return c.store.Close()
+}
@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeOsascript puts an osascript running the shell script body on PATH.
+// The AppleScript it was given is in "$2".
+func fakeOsascript(t *testing.T, body string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncd " + dir + "\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "osascript"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("ITERM_SESSION_ID", filepath.Base(dir)) // A transition token of its own
+	t.Setenv("SSH_CONNECTION", "")
+	t.Setenv("SSH_TTY", "")
+}
+
+// fakeITerm is an osascript remembering the background color it is set
+// to and printing it when asked, like iTerm2
+const fakeITerm = `case "$2" in
+*"set background color"*) echo "$2" | sed -n 's/.*set background color to {\(.*\)}.*/\1/p' > background ;;
+*"get background color"*) cat background ;;
+esac`
+
+// randomRGB16 returns n reproducible random 16-bit colors, plus the
+// extremes
+func randomRGB16(n int) []RGB16 {
+	rng := rand.New(rand.NewSource(1))
+	colors := []RGB16{{}, {R: 65535, G: 65535, B: 65535}, {R: 65535}, {G: 1}, {B: 32768}}
+	for i := 0; i < n; i++ {
+		colors = append(colors, RGB16{R: uint16(rng.Intn(65536)), G: uint16(rng.Intn(65536)), B: uint16(rng.Intn(65536))})
+	}
+	return colors
+}
+
+// memoryConfig returns a configuration that keeps colors in memory
+func memoryConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.Persistence.Backend = "memory"
+	return cfg
+}
+
+func TestTo8To16Idempotent(t *testing.T) {
+	for _, color := range randomRGB16(10000) {
+		once := color.To8().To16()
+		if twice := once.To8().To16(); twice != once {
+			t.Fatalf("%v: To8().To16() gave %v, then %v", color, once, twice)
+		}
+	}
+	for v := 0; v < 256; v++ {
+		rgb := RGB{R: uint8(v), G: uint8(255 - v), B: uint8(v / 2)}
+		if got := rgb.To16().To8(); got != rgb {
+			t.Fatalf("%v: To16().To8() gave %v", rgb, got)
+		}
+	}
+}
+
+// Exact equality means repeated conversions can't drift
+func TestHSVRoundTrip(t *testing.T) {
+	c := NewColorManager(memoryConfig())
+	for _, color := range randomRGB16(100000) {
+		hsv := c.RGB16ToHSV(color)
+		if got := c.HSVToRGB16(hsv.H, hsv.S, hsv.V); got != color {
+			t.Fatalf("%v: HSV %v came back as %v", color, hsv, got)
+		}
+	}
+}
+
+func TestITermRoundTrip(t *testing.T) {
+	fakeOsascript(t, fakeITerm)
+	c := NewColorManager(memoryConfig())
+	for _, color := range randomRGB16(50) {
+		if err := c.SetITermColor16(color); err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.GetCurrentColor16()
+		if err != nil || got != color {
+			t.Fatalf("set %v, got %v, %v", color, got, err)
+		}
+		// Setting what was read leaves it unchanged
+		if err := c.SetITermColor16(got); err != nil {
+			t.Fatal(err)
+		}
+		if again, _ := c.GetCurrentColor16(); again != color {
+			t.Fatalf("set %v again, got %v", got, again)
+		}
+	}
+}
+
+func TestRemoteSessionSetsColorsWithOSC(t *testing.T) {
+	fakeOsascript(t, "exit 1") // Never reached
+	t.Setenv("SSH_CONNECTION", "10.0.0.1 52000 10.0.0.2 22")
+	tty := filepath.Join(t.TempDir(), "tty")
+	if err := os.WriteFile(tty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(path string) { ttyPath = path }(ttyPath)
+	ttyPath = tty
+
+	c := NewColorManager(memoryConfig())
+	color := RGB16{R: 0x1234, G: 0xabcd, B: 0x00ff}
+	if err := c.ApplyColor16(color, ColorChange{Source: "ssh"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(tty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x1b]11;rgb:1234/abcd/00ff\x07"; !strings.HasPrefix(string(data), want) {
+		t.Errorf("terminal got %q, want it to start with %q", data, want)
+	}
+	if !strings.Contains(string(data), "\x1b]10;rgb:") {
+		t.Errorf("terminal got %q without a foreground", data)
+	}
+	if got, err := c.GetCurrentColor16(); err != nil || got != color {
+		t.Errorf("current color is %v, %v; want the applied %v", got, err, color)
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	file, err := NewFileStore(t.TempDir(), DefaultConfig().Persistence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]Store{
+		"memory": NewMemoryStore(DefaultConfig().Persistence),
+		"file":   file,
+	}
+	colors := randomRGB16(200)
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			for i, color := range colors {
+				path := filepath.Join("/projects", name, string(rune('a'+i%26)), "dir")
+				if err := store.SetDirectoryColor(path, color, "directory"); err != nil {
+					t.Fatal(err)
+				}
+				if got, ok := store.GetDirectoryColor(path); !ok || got != color {
+					t.Fatalf("directory %v came back as %v, %v", color, got, ok)
+				}
+				if err := store.SetLastColor(color); err != nil {
+					t.Fatal(err)
+				}
+				if got, ok := store.GetLastColor(); !ok || got != color {
+					t.Fatalf("last color %v came back as %v, %v", color, got, ok)
+				}
+				if err := store.SetLastClaudeColor(color); err != nil {
+					t.Fatal(err)
+				}
+				if got, ok := store.GetLastClaudeColor(); !ok || got != color {
+					t.Fatalf("Claude color %v came back as %v, %v", color, got, ok)
+				}
+			}
+		})
+	}
+}
+
+// Entries written before 16-bit precision only have the 8-bit color
+func TestStoreReadsEntriesWithoutPrecise(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `{"version":1,"values":{"color:directory:/old":{"data":{"color":{"R":18,"G":52,"B":86},"timestamp":"2024-01-01T00:00:00Z","source":"directory"}}}}`
+	if err := os.WriteFile(filepath.Join(dir, "store.json"), []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileStore(dir, DefaultConfig().Persistence)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := store.GetDirectoryColor("/old")
+	want := RGB{R: 18, G: 52, B: 86}.To16()
+	if !ok || got != want {
+		t.Fatalf("legacy entry came back as %v, %v; want %v", got, ok, want)
+	}
+
+	// Rewriting it keeps the value
+	if err := store.SetDirectoryColor("/old", got, "directory"); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := store.GetDirectoryColor("/old"); again != want {
+		t.Fatalf("rewritten entry came back as %v; want %v", again, want)
+	}
+}
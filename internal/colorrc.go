@@ -0,0 +1,299 @@
+package internal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ColorrcName is the name of per-directory override files
+const ColorrcName = ".colorrc"
+
+// DirectorySettings are per-directory overrides declared in .colorrc files.
+// Unset fields are nil and fall back to the configuration.
+type DirectorySettings struct {
+	Color      *RGB    // Fixed color, mapped to the active appearance
+	LightColor *RGB    // Fixed color used as-is in light mode
+	Palette    []RGB   // Colors to pick from by path hash
+	Theme      *string // Named theme whose background is used as-is
+	Saturation *Range  // Directory generator saturation range
+	Value      *Range  // Directory generator value range
+
+	Sources   []string // .colorrc files applied, outermost first
+	Untrusted []string // .colorrc files skipped because they aren't trusted
+}
+
+// IsEmpty reports whether no .colorrc contributed settings
+func (s DirectorySettings) IsEmpty() bool {
+	return len(s.Sources) == 0
+}
+
+// merge applies the settings of a nearer .colorrc over s. A fixed color, a
+// palette and a theme replace each other; generator ranges merge field by
+// field.
+func (s *DirectorySettings) merge(child DirectorySettings) {
+	if child.Color != nil || child.Palette != nil || child.Theme != nil {
+		s.Color, s.LightColor, s.Palette, s.Theme = child.Color, child.LightColor, child.Palette, child.Theme
+	}
+	if child.LightColor != nil {
+		s.LightColor = child.LightColor
+	}
+	if child.Saturation != nil {
+		s.Saturation = child.Saturation
+	}
+	if child.Value != nil {
+		s.Value = child.Value
+	}
+	s.Sources = append(s.Sources, child.Sources...)
+}
+
+// ParseColorrc parses the contents of a .colorrc file
+func ParseColorrc(path string, data []byte) (DirectorySettings, error) {
+	root, err := parseTOML(path, string(data))
+	if err != nil {
+		return DirectorySettings{}, err
+	}
+
+	d := &configDecoder{file: path, noEnv: true}
+	s := d.directorySettings(root, "")
+	d.unknown(root, "")
+
+	if len(d.errs) > 0 {
+		return DirectorySettings{}, errors.Join(d.errs...)
+	}
+	s.Sources = []string{path}
+	return s, nil
+}
+
+// directorySettings decodes the color, palette and generator keys shared by
+// .colorrc files and rules
+func (d *configDecoder) directorySettings(t *tomlTable, section string) DirectorySettings {
+	var s DirectorySettings
+
+	if _, ok := t.Values["color"]; ok {
+		s.Color = new(RGB)
+		d.color(t, section, "color", s.Color)
+	}
+	if _, ok := t.Values["light_color"]; ok {
+		s.LightColor = new(RGB)
+		d.color(t, section, "light_color", s.LightColor)
+	}
+	if v, ok := t.Values["palette"]; ok {
+		var names []string
+		d.strings(t, section, "palette", &names)
+		for _, name := range names {
+			c, err := ParseColor(name)
+			if err != nil {
+				d.fail(v, "", "%s: %v", keyName(section, "palette"), err)
+				continue
+			}
+			s.Palette = append(s.Palette, c)
+		}
+		if len(names) == 0 {
+			d.fail(v, "", "%s must not be empty", keyName(section, "palette"))
+		}
+	}
+	if v, ok := t.Values["theme"]; ok {
+		s.Theme = new(string)
+		d.str(t, section, "theme", s.Theme)
+		if err := ValidateThemeName(*s.Theme); err != nil {
+			d.fail(v, "", "%s: %v", keyName(section, "theme"), err)
+		}
+	}
+	if _, ok := t.Values["saturation"]; ok {
+		s.Saturation = new(Range)
+		d.rangeOf(t, section, "saturation", s.Saturation)
+	}
+	if _, ok := t.Values["value"]; ok {
+		s.Value = new(Range)
+		d.rangeOf(t, section, "value", s.Value)
+	}
+
+	return s
+}
+
+// SetTrustPrompt sets the function asked whether an untrusted .colorrc may
+// be used. Without a prompt, untrusted files are skipped.
+func (c *ColorManager) SetTrustPrompt(prompt func(path string, data []byte) bool) {
+	c.trustPrompt = prompt
+}
+
+// DirectorySettings merges every trusted .colorrc from directoryPath upwards,
+// nearer files overriding farther ones, and then the matching rules from the
+// configuration, higher priorities overriding lower ones. Results are
+// remembered for the lifetime of the manager so the user is asked at most once.
+func (c *ColorManager) DirectorySettings(directoryPath string) DirectorySettings {
+	dir, err := filepath.Abs(directoryPath)
+	if err != nil {
+		return DirectorySettings{}
+	}
+
+	if cached, ok := c.settingsCache[dir]; ok {
+		return cached
+	}
+
+	settings := c.colorrcSettings(dir)
+	matched := c.MatchRules(dir)
+	for i := len(matched) - 1; i >= 0; i-- {
+		settings.merge(matched[i].Settings)
+	}
+
+	if c.settingsCache == nil {
+		c.settingsCache = map[string]DirectorySettings{}
+	}
+	c.settingsCache[dir] = settings
+	return settings
+}
+
+// colorrcSettings merges the trusted .colorrc files from dir upwards
+func (c *ColorManager) colorrcSettings(dir string) DirectorySettings {
+	var settings DirectorySettings
+	if !c.config.Colorrc.Enabled {
+		return settings
+	}
+
+	var files []string
+	for {
+		file := filepath.Join(dir, ColorrcName)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			files = append(files, file)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	trust := LoadTrustStore()
+	for i := len(files) - 1; i >= 0; i-- {
+		file := files[i]
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		if !c.isColorrcTrusted(trust, file, data) {
+			settings.Untrusted = append(settings.Untrusted, file)
+			continue
+		}
+
+		parsed, err := ParseColorrc(file, data)
+		if err != nil {
+			log.Printf("Ignoring invalid %s:\n%v", file, err)
+			continue
+		}
+		settings.merge(parsed)
+	}
+
+	return settings
+}
+
+// isColorrcTrusted checks the allowlist and trust store, asking the trust
+// prompt for unknown files
+func (c *ColorManager) isColorrcTrusted(trust *TrustStore, file string, data []byte) bool {
+	dir := filepath.Dir(file)
+	for _, pattern := range c.config.Colorrc.Allow {
+		if MatchGlob(pattern, dir) {
+			return true
+		}
+	}
+
+	if trust.IsTrusted(file, data) {
+		return true
+	}
+
+	if c.trustPrompt == nil || !c.config.Colorrc.Prompt || !c.trustPrompt(file, data) {
+		return false
+	}
+
+	if err := trust.Trust(file, data); err != nil {
+		log.Printf("Error saving trust for %s: %v", file, err)
+	}
+	return true
+}
+
+// TrustStore records which .colorrc files the user has approved. Entries are
+// bound to the file's content, so any change requires approving it again.
+type TrustStore struct {
+	path    string
+	entries map[string]string // .colorrc path -> sha256 of its content
+}
+
+// LoadTrustStore reads the trust store from the configuration directory
+func LoadTrustStore() *TrustStore {
+	store := &TrustStore{
+		path:    filepath.Join(ConfigDir(), "trusted"),
+		entries: map[string]string{},
+	}
+
+	f, err := os.Open(store.path)
+	if err != nil {
+		return store
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines are "<sha256> <path>"
+		hash, path, ok := strings.Cut(scanner.Text(), " ")
+		if ok {
+			store.entries[path] = hash
+		}
+	}
+	return store
+}
+
+func colorrcHash(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// IsTrusted reports whether file was approved with exactly this content
+func (t *TrustStore) IsTrusted(file string, data []byte) bool {
+	return t.entries[file] == colorrcHash(data)
+}
+
+// Trust approves file with its current content
+func (t *TrustStore) Trust(file string, data []byte) error {
+	t.entries[file] = colorrcHash(data)
+	return t.save()
+}
+
+// Untrust removes file from the store, reporting whether it was present
+func (t *TrustStore) Untrust(file string) (bool, error) {
+	if _, ok := t.entries[file]; !ok {
+		return false, nil
+	}
+	delete(t.entries, file)
+	return true, t.save()
+}
+
+// save writes the store atomically
+func (t *TrustStore) save() error {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(t.entries))
+	for path := range t.entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s %s\n", t.entries[path], path)
+	}
+
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
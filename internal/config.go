@@ -0,0 +1,754 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Range is an inclusive range of generator values
+type Range struct {
+	Min, Max float64
+}
+
+// At returns the value at position t (0-1) within the range
+func (r Range) At(t float64) float64 {
+	return r.Min + t*(r.Max-r.Min)
+}
+
+// Clamp limits v to the range
+func (r Range) Clamp(v float64) float64 {
+	if v < r.Min {
+		return r.Min
+	}
+	if v > r.Max {
+		return r.Max
+	}
+	return v
+}
+
+// GeneratorConfig holds the HSV ranges of the directory generator
+type GeneratorConfig struct {
+	Saturation Range
+	Value      Range
+}
+
+// ClaudeConfig holds the parameters of the Claude theme generator
+type ClaudeConfig struct {
+	Hues       []float64
+	Saturation Range
+	Value      Range
+}
+
+// CycleConfig holds the parameters of `color cycle` variants
+type CycleConfig struct {
+	HueShift   Range   // Hue added by hue_shift
+	Step       float64 // Maximum change of brightness and saturation
+	Saturation Range   // Limits for saturation changes
+	Value      Range   // Limits for brightness changes
+}
+
+// LightConfig holds how canonical dark colors are mapped to light ones
+type LightConfig struct {
+	ValueScale      float64
+	SaturationScale float64
+}
+
+// AppearanceColors holds one fixed color per appearance
+type AppearanceColors struct {
+	Dark, Light RGB
+}
+
+// ColorrcConfig controls per-directory .colorrc files
+type ColorrcConfig struct {
+	Enabled bool
+	Allow   []string // Globs of directories whose .colorrc is trusted without asking
+	Prompt  bool     // Ask before using an untrusted .colorrc in interactive shells
+}
+
+// GitConfig controls repository-aware directory colors
+type GitConfig struct {
+	Enabled         bool    // Hash the repository root instead of the directory
+	SubdirLightness float64 // Value offset per directory below the root
+	MaxDepth        int     // Depth after which the offset stops growing
+}
+
+// Tint is a color blended into directory colors in OKLab space
+type Tint struct {
+	Color    RGB
+	Strength float64 // 0 leaves the color unchanged, 1 replaces it
+}
+
+// BranchConfig controls tints derived from the checked-out git branch
+type BranchConfig struct {
+	Enabled          bool
+	Protected        []string // Globs of branch names given the protected tint
+	ProtectedTint    Tint
+	FeatureHueOffset float64 // Largest hue offset for other branches
+	DetachedTint     Tint
+	RebaseTint       Tint
+	MergeTint        Tint // Also used for cherry-picks, reverts and bisects
+}
+
+// SSHConfig controls hostname colors in SSH sessions
+type SSHConfig struct {
+	Enabled bool    // Color remote shells by hostname
+	Blend   float64 // Share of the host color; 1 replaces the directory color
+}
+
+// PathsConfig controls how directory paths are canonicalized
+type PathsConfig struct {
+	FoldCase bool // Treat paths differing only in case as one directory
+}
+
+// RedisConfig holds the Redis connection settings
+type RedisConfig struct {
+	URL         string   // redis://, rediss:// or unix:// URL; replaces Addresses
+	Addresses   []string // Tried in order when there is no URL
+	Timeout     time.Duration
+	Prefix      string // Prepended to every key
+	TLSCAFile   string // PEM certificates to verify a rediss:// server with
+	TLSInsecure bool   // Skip verifying the server certificate
+}
+
+// PersistenceConfig holds the storage backend, lifetimes and the Redis
+// connection
+type PersistenceConfig struct {
+	Backend      string // One of StoreBackends
+	Path         string // File store directory; empty for StateDir()
+	DirectoryTTL time.Duration
+	ClaudeTTL    time.Duration
+	CycleTTL     time.Duration
+	HistoryLimit int // Color events kept; 0 records none
+	Redis        RedisConfig
+}
+
+// Config holds every tunable of the color generators and persistence
+type Config struct {
+	Path        string // File the config was loaded from; empty for defaults
+	Appearance  Appearance
+	Light       LightConfig
+	Directory   GeneratorConfig
+	Strategy    Strategy // How directory colors are derived
+	Paths       PathsConfig
+	Hierarchy   HierarchyConfig
+	Claude      ClaudeConfig
+	Cycle       CycleConfig
+	Reset       AppearanceColors // Background used by `color reset`
+	Foreground  AppearanceColors // Text color for dark and light backgrounds
+	Transition  TransitionOptions
+	Colorrc     ColorrcConfig
+	Rules       []Rule // Sorted by descending priority
+	Git         GitConfig
+	Branch      BranchConfig
+	Danger      []DangerZone // Checked in file order
+	SSH         SSHConfig
+	Hosts       []Rule // Host rules, sorted by descending priority
+	Schedule    []ScheduleWindow
+	Persistence PersistenceConfig
+}
+
+// DefaultConfig returns the built-in configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Appearance: AppearanceDark,
+		Light: LightConfig{
+			ValueScale:      0.3,
+			SaturationScale: 0.45,
+		},
+		Directory: GeneratorConfig{
+			Saturation: Range{0.5, 0.8},   // More saturated
+			Value:      Range{0.25, 0.45}, // Brighter
+		},
+		Strategy: StrategyHash,
+		Hierarchy: HierarchyConfig{
+			HueStep:   0.04,
+			ValueStep: 0.03,
+		},
+		Claude: ClaudeConfig{
+			Hues:       []float64{0.6, 0.75, 0.85}, // Blue to purple range
+			Saturation: Range{0.4, 0.8},
+			Value:      Range{0.25, 0.4},
+		},
+		Cycle: CycleConfig{
+			HueShift:   Range{0.15, 0.35},
+			Step:       0.4,
+			Saturation: Range{0.2, 0.9},
+			Value:      Range{0.2, 0.8},
+		},
+		Reset: AppearanceColors{
+			Dark:  RGB{R: 30, G: 30, B: 30},
+			Light: RGB{R: 246, G: 246, B: 246},
+		},
+		Foreground: AppearanceColors{
+			Dark:  RGB{R: 220, G: 220, B: 220},
+			Light: RGB{R: 40, G: 40, B: 40},
+		},
+		Transition: DefaultTransitionOptions(),
+		Colorrc: ColorrcConfig{
+			Enabled: true,
+			Prompt:  true,
+		},
+		Git: GitConfig{
+			Enabled:         true,
+			SubdirLightness: 0,
+			MaxDepth:        4,
+		},
+		Branch: BranchConfig{
+			Enabled:          false,
+			Protected:        []string{"main", "master", "release/*"},
+			ProtectedTint:    Tint{Color: RGB{R: 255, G: 59, B: 48}, Strength: 0.15},
+			FeatureHueOffset: 0.03,
+			DetachedTint:     Tint{Color: RGB{R: 142, G: 142, B: 147}, Strength: 0.25},
+			RebaseTint:       Tint{Color: RGB{R: 255, G: 149, B: 0}, Strength: 0.25},
+			MergeTint:        Tint{Color: RGB{R: 255, G: 204, B: 0}, Strength: 0.25},
+		},
+		SSH: SSHConfig{
+			Enabled: true,
+			Blend:   1,
+		},
+		Persistence: PersistenceConfig{
+			Backend:      "file",
+			DirectoryTTL: time.Hour * 24 * 30,
+			ClaudeTTL:    time.Hour * 24,
+			CycleTTL:     time.Hour * 24 * 7,
+			HistoryLimit: 1000,
+			Redis: RedisConfig{
+				Addresses: []string{
+					"localhost:6379", // Default Redis port
+					"127.0.0.1:6379", // Alternative localhost
+					"redis:6379",     // Docker container name
+				},
+				Timeout: time.Second * 2,
+				Prefix:  "color:",
+			},
+		},
+	}
+}
+
+// ConfigDir returns the directory holding the configuration file
+func ConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "color")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "color")
+	}
+	return filepath.Join(home, ".config", "color")
+}
+
+// StateDir returns the directory holding the file store
+func StateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "color")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "color")
+	}
+	return filepath.Join(home, ".local", "state", "color")
+}
+
+// ConfigPath returns the configuration file path, honoring COLOR_CONFIG
+func ConfigPath() string {
+	if path := os.Getenv("COLOR_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(ConfigDir(), "config.toml")
+}
+
+// LoadConfig loads the configuration file, falling back to defaults when it
+// doesn't exist, and applies COLOR_* environment overrides. Every key can be
+// overridden by an environment variable named after its section and key,
+// e.g. COLOR_DIRECTORY_SATURATION="0.4, 0.7" or COLOR_REDIS_TIMEOUT=5s.
+func LoadConfig() (*Config, error) {
+	path := ConfigPath()
+	cfg := DefaultConfig()
+
+	root := newTOMLTable(0)
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if root, err = parseTOML(path, string(data)); err != nil {
+			return nil, err
+		}
+		cfg.Path = path
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	d := &configDecoder{file: path}
+	d.decode(root, cfg)
+	if len(d.errs) > 0 {
+		return nil, errors.Join(d.errs...)
+	}
+	return cfg, nil
+}
+
+// configEnvAliases maps keys to the environment variables used before the
+// configuration file existed
+var configEnvAliases = map[string]string{
+	"transition.enabled": "COLOR_TRANSITION",
+}
+
+// configDecoder decodes and validates a parsed configuration, collecting
+// every error instead of stopping at the first
+type configDecoder struct {
+	file  string
+	noEnv bool // Ignore environment overrides (e.g. for .colorrc files)
+	errs  []error
+}
+
+// decode fills cfg from the root table
+func (d *configDecoder) decode(root *tomlTable, cfg *Config) {
+	if v, src, ok := d.lookup(root, "", "appearance"); ok {
+		if name, ok := d.asString(v, src, "appearance"); ok {
+			if a, err := ParseAppearance(name); err != nil {
+				d.fail(v, src, "%v", err)
+			} else {
+				cfg.Appearance = a
+			}
+		}
+	}
+
+	d.section(root, "light", func(t *tomlTable) {
+		// Light colors are divided by these to map them back to dark
+		d.scale(t, "light", "value_scale", &cfg.Light.ValueScale)
+		d.scale(t, "light", "saturation_scale", &cfg.Light.SaturationScale)
+	})
+
+	d.section(root, "directory", func(t *tomlTable) {
+		d.rangeOf(t, "directory", "saturation", &cfg.Directory.Saturation)
+		d.rangeOf(t, "directory", "value", &cfg.Directory.Value)
+		if v, src, ok := d.lookup(t, "directory", "strategy"); ok {
+			if name, ok := d.asString(v, src, "directory.strategy"); ok {
+				if strategy, err := ParseStrategy(name); err != nil {
+					d.fail(v, src, "%v", err)
+				} else {
+					cfg.Strategy = strategy
+				}
+			}
+		}
+		d.float(t, "directory", "hue_step", &cfg.Hierarchy.HueStep, 0, 0.5)
+		d.float(t, "directory", "value_step", &cfg.Hierarchy.ValueStep, 0, 0.5)
+	})
+
+	d.section(root, "paths", func(t *tomlTable) {
+		d.boolean(t, "paths", "fold_case", &cfg.Paths.FoldCase)
+	})
+
+	d.section(root, "claude", func(t *tomlTable) {
+		d.floats(t, "claude", "hues", &cfg.Claude.Hues, 0, 1)
+		d.rangeOf(t, "claude", "saturation", &cfg.Claude.Saturation)
+		d.rangeOf(t, "claude", "value", &cfg.Claude.Value)
+	})
+
+	d.section(root, "cycle", func(t *tomlTable) {
+		d.rangeOf(t, "cycle", "hue_shift", &cfg.Cycle.HueShift)
+		d.float(t, "cycle", "step", &cfg.Cycle.Step, 0, 1)
+		d.rangeOf(t, "cycle", "saturation", &cfg.Cycle.Saturation)
+		d.rangeOf(t, "cycle", "value", &cfg.Cycle.Value)
+	})
+
+	d.section(root, "reset", func(t *tomlTable) {
+		d.color(t, "reset", "dark", &cfg.Reset.Dark)
+		d.color(t, "reset", "light", &cfg.Reset.Light)
+	})
+
+	d.section(root, "foreground", func(t *tomlTable) {
+		d.color(t, "foreground", "dark", &cfg.Foreground.Dark)
+		d.color(t, "foreground", "light", &cfg.Foreground.Light)
+	})
+
+	d.section(root, "transition", func(t *tomlTable) {
+		d.boolean(t, "transition", "enabled", &cfg.Transition.Enabled)
+		d.duration(t, "transition", "duration", &cfg.Transition.Duration)
+		d.integer(t, "transition", "fps", &cfg.Transition.FPS, 1, 240)
+	})
+
+	d.section(root, "colorrc", func(t *tomlTable) {
+		d.boolean(t, "colorrc", "enabled", &cfg.Colorrc.Enabled)
+		d.strings(t, "colorrc", "allow", &cfg.Colorrc.Allow)
+		d.boolean(t, "colorrc", "prompt", &cfg.Colorrc.Prompt)
+	})
+
+	cfg.Rules = d.decodeRules(root, "rules", "rule")
+	for i, rule := range cfg.Rules {
+		if canonical := canonicalGlob(rule.Pattern); canonical != expandHome(rule.Pattern) {
+			cfg.Rules[i].canonical = canonical
+		}
+	}
+
+	d.section(root, "git", func(t *tomlTable) {
+		d.boolean(t, "git", "enabled", &cfg.Git.Enabled)
+		d.float(t, "git", "subdir_lightness", &cfg.Git.SubdirLightness, -0.2, 0.2)
+		d.integer(t, "git", "max_depth", &cfg.Git.MaxDepth, 0, 100)
+	})
+
+	d.section(root, "branch", func(t *tomlTable) {
+		d.boolean(t, "branch", "enabled", &cfg.Branch.Enabled)
+		d.strings(t, "branch", "protected", &cfg.Branch.Protected)
+		d.tint(t, "branch", "protected", &cfg.Branch.ProtectedTint)
+		d.float(t, "branch", "feature_hue_offset", &cfg.Branch.FeatureHueOffset, 0, 0.5)
+		d.tint(t, "branch", "detached", &cfg.Branch.DetachedTint)
+		d.tint(t, "branch", "rebase", &cfg.Branch.RebaseTint)
+		d.tint(t, "branch", "merge", &cfg.Branch.MergeTint)
+	})
+
+	cfg.Danger = d.decodeDanger(root)
+
+	d.section(root, "ssh", func(t *tomlTable) {
+		d.boolean(t, "ssh", "enabled", &cfg.SSH.Enabled)
+		d.float(t, "ssh", "blend", &cfg.SSH.Blend, 0, 1)
+	})
+
+	cfg.Hosts = d.decodeRules(root, "hosts", "host rule")
+
+	cfg.Schedule = d.decodeSchedule(root)
+
+	d.section(root, "persistence", func(t *tomlTable) {
+		if v, src, ok := d.lookup(t, "persistence", "backend"); ok {
+			if name, ok := d.asString(v, src, "persistence.backend"); ok {
+				if backend, err := ParseBackend(name); err != nil {
+					d.fail(v, src, "%v", err)
+				} else {
+					cfg.Persistence.Backend = backend
+				}
+			}
+		}
+		d.str(t, "persistence", "path", &cfg.Persistence.Path)
+		d.duration(t, "persistence", "directory_ttl", &cfg.Persistence.DirectoryTTL)
+		d.duration(t, "persistence", "claude_ttl", &cfg.Persistence.ClaudeTTL)
+		d.duration(t, "persistence", "cycle_ttl", &cfg.Persistence.CycleTTL)
+		d.integer(t, "persistence", "history_limit", &cfg.Persistence.HistoryLimit, 0, 1000000)
+	})
+
+	d.section(root, "redis", func(t *tomlTable) {
+		if v, src, ok := d.lookup(t, "redis", "url"); ok {
+			if url, ok := d.asString(v, src, "redis.url"); ok {
+				if _, err := ParseRedisURL(url); url != "" && err != nil {
+					d.fail(v, src, "%v", err)
+				} else {
+					cfg.Persistence.Redis.URL = url
+				}
+			}
+		}
+		d.strings(t, "redis", "addresses", &cfg.Persistence.Redis.Addresses)
+		d.duration(t, "redis", "timeout", &cfg.Persistence.Redis.Timeout)
+		if v, src, ok := d.lookup(t, "redis", "prefix"); ok {
+			if prefix, ok := d.asString(v, src, "redis.prefix"); ok {
+				if prefix == "" {
+					d.fail(v, src, "redis.prefix must not be empty")
+				} else {
+					cfg.Persistence.Redis.Prefix = prefix
+				}
+			}
+		}
+		d.str(t, "redis", "tls_ca_file", &cfg.Persistence.Redis.TLSCAFile)
+		d.boolean(t, "redis", "tls_insecure", &cfg.Persistence.Redis.TLSInsecure)
+	})
+
+	d.unknown(root, "")
+}
+
+// section decodes a table, running decode even when the table is absent so
+// environment overrides still apply
+func (d *configDecoder) section(root *tomlTable, name string, decode func(t *tomlTable)) {
+	var t *tomlTable
+	if v, ok := root.get(name); ok {
+		if t, ok = v.Value.(*tomlTable); !ok {
+			d.fail(v, "", "%q must be a table", name)
+			return
+		}
+	}
+	decode(t)
+	d.unknown(t, name)
+}
+
+// unknown reports keys in t that were not consumed
+func (d *configDecoder) unknown(t *tomlTable, section string) {
+	if t == nil {
+		return
+	}
+	for _, key := range t.unused() {
+		d.fail(t.Values[key], "", "unknown key %q", keyName(section, key))
+	}
+}
+
+// keyName returns the dotted name of a key for messages
+func keyName(section, key string) string {
+	if section == "" {
+		return key
+	}
+	return section + "." + key
+}
+
+// envName returns the environment variable overriding section.key
+func envName(section, key string) string {
+	name := key
+	if section != "" {
+		name = section + "_" + key
+	}
+	return "COLOR_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(name))
+}
+
+// lookup returns a value from the environment or the table. src names the
+// environment variable the value came from, if any.
+func (d *configDecoder) lookup(t *tomlTable, section, key string) (tomlValue, string, bool) {
+	// Consume the file value even when overridden so it isn't reported unknown
+	fileValue, inFile := t.get(key)
+	if d.noEnv {
+		return fileValue, "", inFile
+	}
+
+	names := []string{envName(section, key)}
+	if alias, ok := configEnvAliases[keyName(section, key)]; ok {
+		names = append(names, alias)
+	}
+
+	for _, name := range names {
+		if raw, ok := os.LookupEnv(name); ok && raw != "" {
+			v, err := parseTOMLValue(name, raw)
+			if err != nil {
+				v = tomlValue{Value: raw} // Unquoted strings are taken literally
+			}
+			return v, name, true
+		}
+	}
+
+	return fileValue, "", inFile
+}
+
+// fail records a validation error for a value
+func (d *configDecoder) fail(v tomlValue, src, format string, args ...interface{}) {
+	err := &TOMLError{File: d.file, Line: v.Line, Msg: fmt.Sprintf(format, args...)}
+	if src != "" {
+		err = &TOMLError{File: "$" + src, Msg: err.Msg}
+	}
+	d.errs = append(d.errs, err)
+}
+
+// list returns the elements of an array value; comma-separated strings from
+// the environment are accepted as arrays too
+func (d *configDecoder) list(v tomlValue, src, key string) ([]tomlValue, bool) {
+	switch items := v.Value.(type) {
+	case []tomlValue:
+		return items, true
+	case string:
+		if src != "" {
+			var out []tomlValue
+			for _, part := range strings.Split(items, ",") {
+				item, err := parseTOMLValue(src, part)
+				if err != nil {
+					item = tomlValue{Value: strings.TrimSpace(part)}
+				}
+				out = append(out, item)
+			}
+			return out, true
+		}
+	}
+	d.fail(v, src, "%s must be an array", key)
+	return nil, false
+}
+
+func (d *configDecoder) asString(v tomlValue, src, key string) (string, bool) {
+	s, ok := v.Value.(string)
+	if !ok {
+		d.fail(v, src, "%s must be a string", key)
+	}
+	return s, ok
+}
+
+func (d *configDecoder) asFloat(v tomlValue, src, key string) (float64, bool) {
+	switch n := v.Value.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	}
+	d.fail(v, src, "%s must be a number", key)
+	return 0, false
+}
+
+func (d *configDecoder) float(t *tomlTable, section, key string, dst *float64, min, max float64) {
+	v, src, ok := d.lookup(t, section, key)
+	if !ok {
+		return
+	}
+	f, ok := d.asFloat(v, src, keyName(section, key))
+	if !ok {
+		return
+	}
+	if !(f >= min && f <= max) { // Also rejects nan
+		d.fail(v, src, "%s must be between %g and %g", keyName(section, key), min, max)
+		return
+	}
+	*dst = f
+}
+
+// scale decodes a factor greater than 0 and at most 1
+func (d *configDecoder) scale(t *tomlTable, section, key string, dst *float64) {
+	v, src, ok := d.lookup(t, section, key)
+	if !ok {
+		return
+	}
+	f, ok := d.asFloat(v, src, keyName(section, key))
+	if !ok {
+		return
+	}
+	if !(f > 0 && f <= 1) {
+		d.fail(v, src, "%s must be greater than 0 and at most 1", keyName(section, key))
+		return
+	}
+	*dst = f
+}
+
+func (d *configDecoder) floats(t *tomlTable, section, key string, dst *[]float64, min, max float64) {
+	v, src, ok := d.lookup(t, section, key)
+	if !ok {
+		return
+	}
+	items, ok := d.list(v, src, keyName(section, key))
+	if !ok {
+		return
+	}
+	if len(items) == 0 {
+		d.fail(v, src, "%s must not be empty", keyName(section, key))
+		return
+	}
+	var out []float64
+	for _, item := range items {
+		f, ok := d.asFloat(item, src, keyName(section, key))
+		if !ok {
+			return
+		}
+		if !(f >= min && f <= max) {
+			d.fail(item, src, "%s values must be between %g and %g", keyName(section, key), min, max)
+			return
+		}
+		out = append(out, f)
+	}
+	*dst = out
+}
+
+// rangeOf decodes a [min, max] pair within 0-1
+func (d *configDecoder) rangeOf(t *tomlTable, section, key string, dst *Range) {
+	var values []float64
+	d.floats(t, section, key, &values, 0, 1)
+	if values == nil {
+		return
+	}
+	v, src, _ := d.lookup(t, section, key)
+	if len(values) != 2 {
+		d.fail(v, src, "%s must be a [min, max] pair", keyName(section, key))
+		return
+	}
+	if values[0] > values[1] {
+		d.fail(v, src, "%s minimum %g is greater than maximum %g", keyName(section, key), values[0], values[1])
+		return
+	}
+	*dst = Range{Min: values[0], Max: values[1]}
+}
+
+func (d *configDecoder) strings(t *tomlTable, section, key string, dst *[]string) {
+	v, src, ok := d.lookup(t, section, key)
+	if !ok {
+		return
+	}
+	items, ok := d.list(v, src, keyName(section, key))
+	if !ok {
+		return
+	}
+	var out []string
+	for _, item := range items {
+		s, ok := d.asString(item, src, keyName(section, key))
+		if !ok {
+			return
+		}
+		out = append(out, s)
+	}
+	*dst = out
+}
+
+func (d *configDecoder) str(t *tomlTable, section, key string, dst *string) {
+	v, src, ok := d.lookup(t, section, key)
+	if !ok {
+		return
+	}
+	if s, ok := d.asString(v, src, keyName(section, key)); ok {
+		*dst = s
+	}
+}
+
+func (d *configDecoder) boolean(t *tomlTable, section, key string, dst *bool) {
+	v, src, ok := d.lookup(t, section, key)
+	if !ok {
+		return
+	}
+	switch b := v.Value.(type) {
+	case bool:
+		*dst = b
+	case int64:
+		// Accept COLOR_TRANSITION=1 style environment values
+		*dst = b != 0
+	default:
+		d.fail(v, src, "%s must be true or false", keyName(section, key))
+	}
+}
+
+func (d *configDecoder) integer(t *tomlTable, section, key string, dst *int, min, max int) {
+	v, src, ok := d.lookup(t, section, key)
+	if !ok {
+		return
+	}
+	n, ok := v.Value.(int64)
+	if !ok || int(n) < min || int(n) > max {
+		d.fail(v, src, "%s must be an integer between %d and %d", keyName(section, key), min, max)
+		return
+	}
+	*dst = int(n)
+}
+
+func (d *configDecoder) duration(t *tomlTable, section, key string, dst *time.Duration) {
+	v, src, ok := d.lookup(t, section, key)
+	if !ok {
+		return
+	}
+	s, ok := d.asString(v, src, keyName(section, key))
+	if !ok {
+		return
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil || dur <= 0 {
+		d.fail(v, src, "%s.%s must be a positive duration such as \"30s\" or \"24h\"", section, key)
+		return
+	}
+	*dst = dur
+}
+
+func (d *configDecoder) color(t *tomlTable, section, key string, dst *RGB) {
+	v, src, ok := d.lookup(t, section, key)
+	if !ok {
+		return
+	}
+	s, ok := d.asString(v, src, keyName(section, key))
+	if !ok {
+		return
+	}
+	c, err := ParseColor(s)
+	if err != nil {
+		d.fail(v, src, "%s: %v", keyName(section, key), err)
+		return
+	}
+	*dst = c
+}
+
+// tint decodes the <prefix>_tint color and <prefix>_strength amount
+func (d *configDecoder) tint(t *tomlTable, section, prefix string, dst *Tint) {
+	d.color(t, section, prefix+"_tint", &dst.Color)
+	d.float(t, section, prefix+"_strength", &dst.Strength, 0, 1)
+}
@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScheduleWindowMustNotBeEmpty(t *testing.T) {
+	root, err := parseTOML("config.toml", "[[schedule]]\nname = \"night\"\nstart = \"20:00\"\nend = \"20:00\"")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &configDecoder{file: "config.toml", noEnv: true}
+	d.decode(root, DefaultConfig())
+	if len(d.errs) != 1 || !strings.Contains(d.errs[0].Error(), "config.toml:4:") || !strings.Contains(d.errs[0].Error(), "starts and ends at 20:00") {
+		t.Errorf("got errors %v", d.errs)
+	}
+}
+
+func TestRedisPrefixMustNotBeEmpty(t *testing.T) {
+	root, err := parseTOML("config.toml", "[redis]\nprefix = \"\"")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	d := &configDecoder{file: "config.toml", noEnv: true}
+	d.decode(root, cfg)
+	if len(d.errs) != 1 || !strings.Contains(d.errs[0].Error(), "redis.prefix must not be empty") {
+		t.Errorf("got errors %v", d.errs)
+	}
+	if cfg.Persistence.Redis.Prefix != "color:" {
+		t.Errorf("prefix is %q, want the default kept", cfg.Persistence.Redis.Prefix)
+	}
+}
@@ -0,0 +1,222 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DangerZone maps a deployment context, such as a Kubernetes context or a
+// cloud profile, to an alert color that overrides directory colors while
+// the context is active
+type DangerZone struct {
+	Name       string
+	Source     string         // "kube", "aws", "gcp", "azure" or "env:NAME"
+	Pattern    string         // Glob matched against the detected value
+	Regex      *regexp.Regexp // Alternative to Pattern
+	Color      RGB            // Alert color, mapped to the active appearance
+	LightColor *RGB           // Alert color used as-is in light mode
+	Line       int            // Line in the configuration file
+}
+
+// DangerMatch is an active danger zone and the value that triggered it
+type DangerMatch struct {
+	Zone  DangerZone
+	Value string
+}
+
+// Reason describes why the danger zone is active
+func (m DangerMatch) Reason() string {
+	name := m.Zone.Name
+	if name == "" {
+		name = fmt.Sprintf("danger zone on line %d", m.Zone.Line)
+	}
+	return fmt.Sprintf("%s (%s %q)", name, sourceLabel(m.Zone.Source), m.Value)
+}
+
+// Matches reports whether a detected value falls in the danger zone
+func (z DangerZone) Matches(value string) bool {
+	if value == "" {
+		return false
+	}
+	if z.Regex != nil {
+		return z.Regex.MatchString(value)
+	}
+	return MatchGlob(z.Pattern, value)
+}
+
+// dangerEnv lists the variables read for each cloud source, in order
+var dangerEnv = map[string][]string{
+	"aws":   {"AWS_PROFILE", "AWS_DEFAULT_PROFILE"},
+	"gcp":   {"GOOGLE_CLOUD_PROJECT", "CLOUDSDK_CORE_PROJECT"},
+	"azure": {"ARM_SUBSCRIPTION_ID", "AZURE_SUBSCRIPTION_ID"},
+}
+
+// sourceLabel returns a human-readable name for a danger zone source
+func sourceLabel(source string) string {
+	switch source {
+	case "kube":
+		return "kube context"
+	case "aws":
+		return "AWS profile"
+	case "gcp":
+		return "GCP project"
+	case "azure":
+		return "Azure subscription"
+	}
+	return strings.TrimPrefix(source, "env:")
+}
+
+// validDangerSource reports whether source names a known detector
+func validDangerSource(source string) bool {
+	if source == "kube" {
+		return true
+	}
+	if _, ok := dangerEnv[source]; ok {
+		return true
+	}
+	name, ok := strings.CutPrefix(source, "env:")
+	return ok && name != ""
+}
+
+// DetectContext returns the current value of a danger zone source, or an
+// empty string when it isn't set
+func DetectContext(source string) string {
+	if source == "kube" {
+		return KubeContext()
+	}
+	if name, ok := strings.CutPrefix(source, "env:"); ok {
+		return os.Getenv(name)
+	}
+	for _, name := range dangerEnv[source] {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// KubeContext reads current-context from the files in $KUBECONFIG, or from
+// ~/.kube/config. Like kubectl, the first file that sets it wins.
+func KubeContext() string {
+	files := filepath.SplitList(os.Getenv("KUBECONFIG"))
+	if len(files) == 0 {
+		files = []string{expandHome("~/.kube/config")}
+	}
+
+	for _, file := range files {
+		if context := readKubeContext(file); context != "" {
+			return context
+		}
+	}
+	return ""
+}
+
+// readKubeContext finds the top-level current-context key of a kubeconfig
+// without a full YAML parser
+func readKubeContext(file string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "current-context:")
+		if !ok {
+			continue
+		}
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		return strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return ""
+}
+
+// ActiveDangerZone returns the first configured danger zone whose source
+// currently matches
+func (c *ColorManager) ActiveDangerZone() (DangerMatch, bool) {
+	detected := map[string]string{}
+	for _, zone := range c.config.Danger {
+		value, ok := detected[zone.Source]
+		if !ok {
+			value = DetectContext(zone.Source)
+			detected[zone.Source] = value
+		}
+		if zone.Matches(value) {
+			return DangerMatch{Zone: zone, Value: value}, true
+		}
+	}
+	return DangerMatch{}, false
+}
+
+// dangerColor returns the alert color of the active danger zone
+func (c *ColorManager) dangerColor() (RGB, bool) {
+	match, ok := c.ActiveDangerZone()
+	if !ok {
+		return RGB{}, false
+	}
+	if c.Appearance() == AppearanceLight && match.Zone.LightColor != nil {
+		return *match.Zone.LightColor, true
+	}
+	return c.toAppearance(match.Zone.Color), true
+}
+
+// decodeDanger decodes the [[danger]] array of tables
+func (d *configDecoder) decodeDanger(root *tomlTable) []DangerZone {
+	v, ok := root.get("danger")
+	if !ok {
+		return nil
+	}
+	tables, ok := v.Value.([]*tomlTable)
+	if !ok {
+		d.fail(v, "", "danger must be declared as [[danger]] tables")
+		return nil
+	}
+
+	var zones []DangerZone
+	for _, t := range tables {
+		// Danger zone keys are never overridden from the environment
+		sub := &configDecoder{file: d.file, noEnv: true}
+		zone := DangerZone{Line: t.Line}
+
+		sub.str(t, "danger", "name", &zone.Name)
+		sub.str(t, "danger", "source", &zone.Source)
+		if !validDangerSource(zone.Source) {
+			sub.fail(tomlValue{Line: t.Line}, "", "danger.source must be kube, aws, gcp, azure or env:NAME, got %q", zone.Source)
+		}
+
+		sub.str(t, "danger", "pattern", &zone.Pattern)
+		var expr string
+		sub.str(t, "danger", "regex", &expr)
+		if expr != "" {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				sub.fail(t.Values["regex"], "", "danger.regex: %v", err)
+			}
+			zone.Regex = re
+		}
+		if (zone.Pattern == "") == (expr == "") {
+			sub.fail(tomlValue{Line: t.Line}, "", "danger zone must have exactly one of pattern or regex")
+		}
+
+		if _, ok := t.Values["color"]; !ok {
+			sub.fail(tomlValue{Line: t.Line}, "", "danger zone must have a color")
+		}
+		sub.color(t, "danger", "color", &zone.Color)
+		if _, ok := t.Values["light_color"]; ok {
+			zone.LightColor = new(RGB)
+			sub.color(t, "danger", "light_color", zone.LightColor)
+		}
+		sub.unknown(t, "danger")
+
+		d.errs = append(d.errs, sub.errs...)
+		zones = append(zones, zone)
+	}
+	return zones
+}
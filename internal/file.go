@@ -0,0 +1,423 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// storeFileVersion is the format version of the file store
+const storeFileVersion = 1
+
+// FileStore keeps colors in a JSON file under the state directory. Every
+// operation reloads the file under an advisory lock, so concurrent shells
+// see each other's colors. Writes replace the file atomically and drop
+// expired entries, keeping it compact. The color history is appended to
+// a separate events file instead, so recording an applied color never
+// rewrites the store.
+type FileStore struct {
+	mu     sync.Mutex
+	path   string
+	lock   string
+	events string
+	mem    *MemoryStore
+	loaded os.FileInfo // The file as last loaded or saved
+}
+
+// storeFile is the on-disk layout of the file store
+type storeFile struct {
+	Version int                    `json:"version"`
+	Values  map[string]memoryValue `json:"values"`
+}
+
+// NewFileStore opens the file store in dir, creating the directory
+func NewFileStore(dir string, cfg PersistenceConfig) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("can't create store directory: %w", err)
+	}
+	fs := &FileStore{
+		path:   filepath.Join(dir, "store.json"),
+		lock:   filepath.Join(dir, "store.lock"),
+		events: filepath.Join(dir, "events.jsonl"),
+		mem:    NewMemoryStore(cfg),
+	}
+
+	// Fail now rather than on every operation when the lock can't be made
+	f, err := os.OpenFile(fs.lock, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("can't open store lock: %w", err)
+	}
+	f.Close()
+	return fs, nil
+}
+
+// withLock runs fn with the store file loaded and locked
+func (fs *FileStore) withLock(exclusive bool, fn func() error) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, err := os.OpenFile(fs.lock, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f, exclusive); err != nil {
+		return fmt.Errorf("can't lock %s: %w", fs.lock, err)
+	}
+	defer unlockFile(f)
+
+	if err := fs.load(); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// load reads the store file unless it is unchanged since it was last
+// loaded. An unreadable file is moved aside so the store keeps working.
+func (fs *FileStore) load() error {
+	info, err := os.Stat(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		fs.mem.values = map[string]memoryValue{}
+		fs.loaded = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fs.loaded != nil && os.SameFile(info, fs.loaded) && info.ModTime().Equal(fs.loaded.ModTime()) && info.Size() == fs.loaded.Size() {
+		return nil
+	}
+
+	data, err := os.ReadFile(fs.path)
+	if err != nil {
+		return err
+	}
+	file := storeFile{Values: map[string]memoryValue{}}
+	if err := json.Unmarshal(data, &file); err != nil || file.Version > storeFileVersion {
+		if err == nil {
+			err = fmt.Errorf("version %d is newer than this tool supports", file.Version)
+		}
+		aside := fs.path + ".corrupt"
+		log.Printf("Moving unreadable store %s to %s: %v", fs.path, aside, err)
+		if err := os.Rename(fs.path, aside); err != nil {
+			return err
+		}
+		file.Values = map[string]memoryValue{}
+		info = nil
+	}
+	if file.Values == nil {
+		file.Values = map[string]memoryValue{}
+	}
+
+	fs.mem.values = file.Values
+	fs.loaded = info
+	return nil
+}
+
+// save writes the store atomically, dropping expired values first
+func (fs *FileStore) save() error {
+	for key := range fs.mem.values {
+		fs.mem.get(key) // Removes the value if it expired
+	}
+
+	data, err := json.Marshal(storeFile{Version: storeFileVersion, Values: fs.mem.values})
+	if err != nil {
+		return fmt.Errorf("error marshaling store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fs.path), "store-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), fs.path); err != nil {
+		return err
+	}
+
+	fs.loaded, _ = os.Stat(fs.path)
+	return nil
+}
+
+// read runs a lookup against the current file contents
+func (fs *FileStore) read(fn func()) {
+	err := fs.withLock(false, func() error {
+		fn()
+		return nil
+	})
+	if err != nil {
+		log.Printf("File store error: %v", err)
+	}
+}
+
+// write runs a change and saves the store when it succeeds. When either
+// fails the file is read again next time, dropping what wasn't saved.
+func (fs *FileStore) write(fn func() error) error {
+	return fs.withLock(true, func() error {
+		err := fn()
+		if err == nil {
+			err = fs.save()
+		}
+		if err != nil {
+			fs.loaded = nil
+		}
+		return err
+	})
+}
+
+// GetDirectoryColor retrieves stored color for a directory
+func (fs *FileStore) GetDirectoryColor(directoryPath string) (color RGB16, ok bool) {
+	fs.read(func() { color, ok = fs.mem.GetDirectoryColor(directoryPath) })
+	return color, ok
+}
+
+// SetDirectoryColor stores color for a directory, recording where it
+// came from
+func (fs *FileStore) SetDirectoryColor(directoryPath string, color RGB16, source string) error {
+	return fs.write(func() error { return fs.mem.SetDirectoryColor(directoryPath, color, source) })
+}
+
+// CountDirectoryColors counts the directory colors that haven't expired
+func (fs *FileStore) CountDirectoryColors() (n int, err error) {
+	err = fs.withLock(false, func() error {
+		n, err = fs.mem.CountDirectoryColors()
+		return err
+	})
+	return n, err
+}
+
+// GetRerollHistory retrieves the colors a directory had before each
+// reroll, oldest first
+func (fs *FileStore) GetRerollHistory(directoryPath string) (history []RGB16) {
+	fs.read(func() { history = fs.mem.GetRerollHistory(directoryPath) })
+	return history
+}
+
+// SetRerollHistory stores the reroll history of a directory
+func (fs *FileStore) SetRerollHistory(directoryPath string, history []RGB16) error {
+	return fs.write(func() error { return fs.mem.SetRerollHistory(directoryPath, history) })
+}
+
+// GetLastClaudeColor retrieves the last used Claude theme color
+func (fs *FileStore) GetLastClaudeColor() (color RGB16, ok bool) {
+	fs.read(func() { color, ok = fs.mem.GetLastClaudeColor() })
+	return color, ok
+}
+
+// SetLastClaudeColor stores the last used Claude theme color
+func (fs *FileStore) SetLastClaudeColor(color RGB16) error {
+	return fs.write(func() error { return fs.mem.SetLastClaudeColor(color) })
+}
+
+// GetCycleState retrieves the harmony walk state of `color cycle`
+func (fs *FileStore) GetCycleState() (state CycleState, ok bool) {
+	fs.read(func() { state, ok = fs.mem.GetCycleState() })
+	return state, ok
+}
+
+// SetCycleState stores the harmony walk state of `color cycle`
+func (fs *FileStore) SetCycleState(state CycleState) error {
+	return fs.write(func() error { return fs.mem.SetCycleState(state) })
+}
+
+// GetLastColor retrieves the last color applied to the terminal
+func (fs *FileStore) GetLastColor() (color RGB16, ok bool) {
+	fs.read(func() { color, ok = fs.mem.GetLastColor() })
+	return color, ok
+}
+
+// SetLastColor stores the last color applied to the terminal
+func (fs *FileStore) SetLastColor(color RGB16) error {
+	return fs.write(func() error { return fs.mem.SetLastColor(color) })
+}
+
+// AddEvent appends an applied color to the events file. Once the file
+// holds about persistence.history_limit events it replaces the previous
+// one (events.jsonl.1), so between one and two limits' worth are on disk.
+func (fs *FileStore) AddEvent(event ColorEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshaling event: %w", err)
+	}
+	line = append(line, '\n')
+
+	return fs.withLock(true, func() error {
+		f, err := os.OpenFile(fs.events, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(line); err != nil {
+			f.Close()
+			return err
+		}
+		info, err := f.Stat()
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		// Sized by the newest event, which keeps rotation to one stat
+		if info.Size() >= int64(fs.mem.config.HistoryLimit*len(line)) {
+			return os.Rename(fs.events, fs.events+".1")
+		}
+		return nil
+	})
+}
+
+// GetEvents retrieves the newest persistence.history_limit events
+// matching filter, newest first
+func (fs *FileStore) GetEvents(filter EventFilter) (events []ColorEvent, err error) {
+	err = fs.withLock(false, func() error {
+		var all []ColorEvent
+		for _, path := range []string{fs.events + ".1", fs.events} {
+			read, err := readEvents(path)
+			if err != nil {
+				return err
+			}
+			all = append(all, read...)
+		}
+		if limit := fs.mem.config.HistoryLimit; len(all) > limit {
+			all = all[len(all)-limit:]
+		}
+
+		for i := len(all) - 1; i >= 0 && !filter.Full(events); i-- {
+			if filter.Match(all[i]) {
+				events = append(events, all[i])
+			}
+		}
+		return nil
+	})
+	return events, err
+}
+
+// readEvents reads an events file, oldest first. A missing file has no
+// events, and lines that can't be read (such as one cut short by a
+// crash) are skipped.
+func readEvents(path string) ([]ColorEvent, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ColorEvent
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var event ColorEvent
+		if err := json.Unmarshal(line, &event); err == nil {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// SetPin pins a directory color. Pins never expire.
+func (fs *FileStore) SetPin(directoryPath string, color RGB16, subtree bool) error {
+	return fs.write(func() error { return fs.mem.SetPin(directoryPath, color, subtree) })
+}
+
+// GetPin retrieves the pin covering a directory: its own, or the nearest
+// subtree pin of an ancestor
+func (fs *FileStore) GetPin(directoryPath string) (pin Pin, ok bool) {
+	fs.read(func() { pin, ok = fs.mem.GetPin(directoryPath) })
+	return pin, ok
+}
+
+// DeletePin removes a directory's own pin, reporting whether it had one
+func (fs *FileStore) DeletePin(directoryPath string) (removed bool, err error) {
+	err = fs.write(func() error {
+		removed, err = fs.mem.DeletePin(directoryPath)
+		return err
+	})
+	return removed, err
+}
+
+// ListPins retrieves every pinned directory sorted by path
+func (fs *FileStore) ListPins() (pins []Pin, err error) {
+	err = fs.withLock(false, func() error {
+		pins, err = fs.mem.ListPins()
+		return err
+	})
+	return pins, err
+}
+
+// GetTheme retrieves a saved theme
+func (fs *FileStore) GetTheme(name string) (theme Theme, ok bool, err error) {
+	err = fs.withLock(false, func() error {
+		theme, ok, err = fs.mem.GetTheme(name)
+		return err
+	})
+	return theme, ok, err
+}
+
+// SetTheme saves a theme. Themes never expire.
+func (fs *FileStore) SetTheme(theme Theme) error {
+	return fs.write(func() error { return fs.mem.SetTheme(theme) })
+}
+
+// ListThemes retrieves every saved theme sorted by name
+func (fs *FileStore) ListThemes() (themes []Theme, err error) {
+	err = fs.withLock(false, func() error {
+		themes, err = fs.mem.ListThemes()
+		return err
+	})
+	return themes, err
+}
+
+// DeleteTheme removes a saved theme, reporting whether it existed
+func (fs *FileStore) DeleteTheme(name string) (removed bool, err error) {
+	err = fs.write(func() error {
+		removed, err = fs.mem.DeleteTheme(name)
+		return err
+	})
+	return removed, err
+}
+
+// ClearColorCache removes all stored colors and the color history. Saved
+// themes are kept, and so are pinned directory colors unless all is set.
+func (fs *FileStore) ClearColorCache(all bool) error {
+	return fs.write(func() error {
+		for _, path := range []string{fs.events, fs.events + ".1"} {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		return fs.mem.ClearColorCache(all)
+	})
+}
+
+// MigrateDirectoryKeys rewrites every directory key to the key canonical
+// returns for its path
+func (fs *FileStore) MigrateDirectoryKeys(canonical func(path string) (string, bool), force bool) (result MigrationResult, err error) {
+	err = fs.write(func() error {
+		result, err = fs.mem.MigrateDirectoryKeys(canonical, force)
+		return err
+	})
+	return result, err
+}
+
+// GetConnectionStatus describes where the store is kept
+func (fs *FileStore) GetConnectionStatus() string {
+	return fmt.Sprintf("✅ File store at %s (colors will persist)", fs.path)
+}
+
+// Close does nothing; every write is already on disk
+func (fs *FileStore) Close() error {
+	return nil
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStoreDropsFailedWrites(t *testing.T) {
+	store, err := NewFileStore(t.TempDir(), DefaultConfig().Persistence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := RGB{R: 0x12, G: 0x34, B: 0x56}.To16()
+	if err := store.SetLastColor(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err = store.write(func() error {
+		store.mem.SetLastColor(RGB{R: 0xff}.To16())
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("write returned %v, want %v", err, failed)
+	}
+	if got, ok := store.GetLastColor(); !ok || got != saved {
+		t.Errorf("last color is %v, %v after a failed write; want the saved %v", got, ok, saved)
+	}
+}
+
+func TestFileStoreKeepsEventsOutOfTheStore(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig().Persistence
+	cfg.HistoryLimit = 10
+	store, err := NewFileStore(dir, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 35 {
+		event := ColorEvent{Time: time.Unix(int64(i), 0).UTC(), Source: "set", New: RGB16{R: uint16(i)}}
+		if err := store.AddEvent(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "store.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("recording events wrote the store file (%v)", err)
+	}
+
+	events, err := store.GetEvents(EventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != cfg.HistoryLimit {
+		t.Fatalf("got %d events, want the newest %d", len(events), cfg.HistoryLimit)
+	}
+	for i, event := range events {
+		if want := uint16(34 - i); event.New.R != want {
+			t.Errorf("event %d is %v, want %d", i, event.New.R, want)
+		}
+	}
+
+	if err := store.ClearColorCache(false); err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := store.GetEvents(EventFilter{}); len(events) != 0 {
+		t.Errorf("history has %d events after a clear", len(events))
+	}
+}
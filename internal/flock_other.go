@@ -0,0 +1,16 @@
+//go:build !unix
+
+package internal
+
+import "os"
+
+// lockFile does nothing where flock isn't available; writes are still
+// atomic, but concurrent writers can lose each other's changes
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
+
+// unlockFile does nothing where flock isn't available
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GitRepo describes the git repository enclosing a directory. It is found by
+// reading .git files and directories directly, without running git.
+type GitRepo struct {
+	Root      string // Working tree root, the directory containing .git
+	GitDir    string // Git directory of this working tree
+	CommonDir string // Git directory shared by all worktrees
+	Worktree  bool   // A linked worktree created by `git worktree add`
+	Submodule bool   // A submodule checked out inside another repository
+}
+
+// FindGitRepo walks up from path to the nearest git working tree
+func FindGitRepo(path string) (*GitRepo, bool) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false
+	}
+
+	for {
+		if repo, ok := readGitDir(dir); ok {
+			return repo, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, false
+		}
+		dir = parent
+	}
+}
+
+// readGitDir inspects dir/.git, which is either the git directory itself or
+// a file containing "gitdir: <path>" for worktrees and submodules
+func readGitDir(dir string) (*GitRepo, bool) {
+	dotGit := filepath.Join(dir, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return nil, false
+	}
+
+	if info.IsDir() {
+		if !isGitDir(dotGit) {
+			return nil, false
+		}
+		return &GitRepo{Root: dir, GitDir: dotGit, CommonDir: dotGit}, true
+	}
+
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return nil, false
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return nil, false
+	}
+	gitDir := resolveRelative(dir, strings.TrimSpace(target))
+
+	repo := &GitRepo{Root: dir, GitDir: gitDir, CommonDir: gitDir}
+
+	// Linked worktrees point at <common>/worktrees/<name>, which names the
+	// shared git directory in its commondir file
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		repo.CommonDir = resolveRelative(gitDir, strings.TrimSpace(string(common)))
+		repo.Worktree = true
+	} else if strings.Contains(filepath.ToSlash(gitDir), "/modules/") {
+		// Submodules keep their git directory in <parent>/.git/modules/<name>
+		repo.Submodule = true
+	}
+
+	return repo, true
+}
+
+// isGitDir reports whether dir looks like a git directory
+func isGitDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "HEAD"))
+	return err == nil
+}
+
+// resolveRelative resolves path against base unless it is absolute
+func resolveRelative(base, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
+
+// ProjectRoot returns the directory identifying the project. Linked
+// worktrees share the main working tree's root so every checkout of a
+// project gets the same color; submodules are projects of their own.
+func (r *GitRepo) ProjectRoot() string {
+	if r.Worktree && filepath.Base(r.CommonDir) == ".git" {
+		return filepath.Dir(r.CommonDir)
+	}
+	if r.Worktree {
+		return r.CommonDir // Worktree of a bare repository
+	}
+	return r.Root
+}
+
+// Depth returns how many directories path is below the working tree root
+func (r *GitRepo) Depth(path string) int {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return 0
+	}
+	rel, err := filepath.Rel(r.Root, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return 0
+	}
+	return len(strings.Split(rel, string(filepath.Separator)))
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// canonicalGlob resolves symlinks in the literal directories a pattern
+// starts with, so it matches the canonical paths directories are colored
+// by. Patterns whose literal part can't be resolved are returned as written.
+func canonicalGlob(pattern string) string {
+	pattern = expandHome(pattern)
+	dir := pattern
+	if i := strings.IndexAny(pattern, "*?["); i >= 0 {
+		dir = pattern[:strings.LastIndexByte(pattern[:i], '/')+1]
+	}
+	dir = strings.TrimSuffix(dir, "/")
+	if !filepath.IsAbs(dir) {
+		return pattern
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return pattern
+	}
+	return resolved + pattern[len(dir):]
+}
+
+// globToRegexp compiles a path glob to a regular expression. "*" and "?"
+// don't cross path separators, "**" matches any number of directories and
+// "[...]" is a character class.
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	pattern = expandHome(pattern)
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				// "**/" also matches no directories at all
+				i++
+				b.WriteString("(?:.*/)?")
+			} else {
+				b.WriteString(".*")
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// MatchGlob reports whether path matches the glob pattern. A pattern ending
+// in "/**" also matches the directory itself.
+func MatchGlob(pattern, path string) bool {
+	re, err := globToRegexp(pattern)
+	if err != nil {
+		return false
+	}
+	if re.MatchString(path) {
+		return true
+	}
+	return strings.HasSuffix(pattern, "/**") && re.MatchString(path+"/")
+}
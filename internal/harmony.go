@@ -0,0 +1,89 @@
+package internal
+
+import "math"
+
+// harmonyHueOffsets lists the hue offsets (in turns) of each harmony mode,
+// starting with the base color itself
+var harmonyHueOffsets = map[string][]float64{
+	"analogous":           {0, 1.0 / 12, -1.0 / 12},
+	"triadic":             {0, 1.0 / 3, 2.0 / 3},
+	"split_complementary": {0, 5.0 / 12, 7.0 / 12},
+	"tetradic":            {0, 1.0 / 6, 1.0 / 2, 2.0 / 3},
+}
+
+// monochromaticValueOffsets lists the value offsets of the monochromatic set
+var monochromaticValueOffsets = []float64{0, 0.08, 0.16, -0.08}
+
+// HarmonyModes lists the harmony-based cycle modes in display order
+var HarmonyModes = []string{"analogous", "triadic", "split_complementary", "tetradic", "monochromatic"}
+
+// IsHarmonyMode reports whether mode walks a harmony set
+func IsHarmonyMode(mode string) bool {
+	for _, m := range HarmonyModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// HarmonySet returns the colors of a harmony mode relative to base, with
+// base itself first
+func (c *ColorManager) HarmonySet(base RGB, mode string) []RGB {
+	var set []RGB
+	for _, color := range c.HarmonySet16(base.To16(), mode) {
+		set = append(set, color.To8())
+	}
+	return set
+}
+
+// HarmonySet16 is HarmonySet at full precision
+func (c *ColorManager) HarmonySet16(base RGB16, mode string) []RGB16 {
+	hsv := c.RGB16ToHSV(c.fromAppearance16(base))
+
+	var set []RGB16
+	if mode == "monochromatic" {
+		for _, offset := range monochromaticValueOffsets {
+			v := math.Max(0.15, math.Min(0.8, hsv.V+offset))
+			set = append(set, c.toAppearance16(c.HSVToRGB16(hsv.H, hsv.S, v)))
+		}
+		set[0] = base
+		return set
+	}
+
+	for _, offset := range harmonyHueOffsets[mode] {
+		set = append(set, c.toAppearance16(c.HSVToRGB16(hsv.H+offset, hsv.S, hsv.V)))
+	}
+	if len(set) > 0 {
+		set[0] = base
+	}
+	return set
+}
+
+// NextHarmonyColor returns the next color of a harmony set. The base color
+// and position are remembered between invocations, so successive calls walk
+// the set instead of drifting. The walk restarts from current whenever the
+// terminal color was changed by something else.
+func (c *ColorManager) NextHarmonyColor(current RGB16, mode string) (RGB16, int, int) {
+	state := CycleState{Base: current, Mode: mode, Last: current}
+	if stored, found := c.store.GetCycleState(); found && stored.Mode == mode && stored.Last == current {
+		state = stored
+	}
+
+	set := c.HarmonySet16(state.Base, mode)
+	state.Index = (state.Index + 1) % len(set)
+	state.Last = set[state.Index]
+
+	c.store.SetCycleState(state)
+
+	return state.Last, state.Index + 1, len(set)
+}
+
+// HarmonyBase returns the remembered base color for mode, or current when the
+// walk would restart
+func (c *ColorManager) HarmonyBase(current RGB16, mode string) RGB16 {
+	if stored, found := c.store.GetCycleState(); found && stored.Last == current && (mode == "" || stored.Mode == mode) {
+		return stored.Base
+	}
+	return current
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Strategy selects how directory colors are derived
+type Strategy string
+
+const (
+	// StrategyHash hashes every directory (or git project) independently
+	StrategyHash Strategy = "hash"
+	// StrategyHierarchical derives subdirectory colors from their parent
+	StrategyHierarchical Strategy = "hierarchical"
+)
+
+// ParseStrategy parses a strategy name
+func ParseStrategy(name string) (Strategy, error) {
+	switch Strategy(strings.ToLower(strings.TrimSpace(name))) {
+	case "", StrategyHash:
+		return StrategyHash, nil
+	case StrategyHierarchical:
+		return StrategyHierarchical, nil
+	}
+	return "", fmt.Errorf("unknown strategy %q (expected hash or hierarchical)", name)
+}
+
+// HierarchyConfig holds the per-level offsets of the hierarchical strategy
+type HierarchyConfig struct {
+	HueStep   float64 // Largest hue offset added per level
+	ValueStep float64 // Largest value offset added per level
+}
+
+// HierarchyAnchor returns the directory a hierarchical color starts from and
+// the names of the directories leading from it to directoryPath. The anchor
+// is the git project root, or else the topmost directory below the home
+// directory or the filesystem root.
+func (c *ColorManager) HierarchyAnchor(directoryPath string) (string, []string) {
+	path, err := filepath.Abs(directoryPath)
+	if err != nil {
+		return directoryPath, nil
+	}
+
+	if c.config.Git.Enabled {
+		if repo, ok := FindGitRepo(path); ok {
+			return repo.ProjectRoot(), relativeNames(repo.Root, path)
+		}
+	}
+
+	base := string(filepath.Separator)
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, home+string(filepath.Separator)) {
+		base = home
+	}
+	names := relativeNames(base, path)
+	if len(names) == 0 {
+		return path, nil
+	}
+	return filepath.Join(base, names[0]), names[1:]
+}
+
+// relativeNames splits the path from base to path into directory names
+func relativeNames(base, path string) []string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	return strings.Split(rel, string(filepath.Separator))
+}
+
+// deriveHierarchy derives a color level by level from an anchor's color.
+// For each directory name, the first four bytes of its md5 give a hue offset
+// and the fifth a value offset, each scaled to -step..+step. Values stay
+// within the generator's value range, widened to include the anchor's.
+func (c *ColorManager) deriveHierarchy(anchor RGB16, names []string, settings DirectorySettings) RGB16 {
+	value := c.config.Directory.Value
+	if settings.Value != nil {
+		value = *settings.Value
+	}
+	hsv := c.RGB16ToHSV(anchor)
+	value.Min = math.Min(value.Min, hsv.V)
+	value.Max = math.Max(value.Max, hsv.V)
+
+	step := c.config.Hierarchy
+	for _, name := range names {
+		sum := md5.Sum([]byte(c.pathKey(name)))
+		hueOffset := float64(binary.BigEndian.Uint32(sum[:4]))/float64(0xFFFFFFFF)*2 - 1
+		valueOffset := float64(sum[4])/255*2 - 1
+
+		hsv.H = math.Mod(hsv.H+hueOffset*step.HueStep+1, 1)
+		hsv.V = value.Clamp(hsv.V + valueOffset*step.ValueStep)
+	}
+	return c.HSVToRGB16(hsv.H, hsv.S, hsv.V)
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// EventSources lists what can apply a color, as recorded in the history
+var EventSources = []string{"directory", "claude", "cycle", "set", "reset", "ssh", "theme", "reroll", "pin"}
+
+// ColorChange describes why a color is being applied
+type ColorChange struct {
+	Source  string // One of EventSources
+	Mode    string // Variant, harmony or theme name, when there is one
+	Path    string // Directory the color belongs to; the working directory when empty
+	Command string // Command run under the color by `color wrap` or `color ssh`
+}
+
+// ColorEvent records a color applied to the terminal
+type ColorEvent struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Mode    string    `json:"mode,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	Backend string    `json:"backend"`
+	Old     *RGB16    `json:"old,omitempty"` // The previously applied color, if known
+	New     RGB16     `json:"new"`
+	Command string    `json:"command,omitempty"`
+}
+
+// EventFilter selects events from the history. Zero fields match
+// everything.
+type EventFilter struct {
+	Since  time.Time
+	Source string
+	Path   string // This directory and everything below it
+	Limit  int
+}
+
+// ParseEventSource validates an event source name
+func ParseEventSource(name string) (string, error) {
+	for _, source := range EventSources {
+		if name == source {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown source %q (expected %s)", name, strings.Join(EventSources, ", "))
+}
+
+// Match reports whether an event passes the filter
+func (f EventFilter) Match(event ColorEvent) bool {
+	if !f.Since.IsZero() && event.Time.Before(f.Since) {
+		return false
+	}
+	if f.Source != "" && event.Source != f.Source {
+		return false
+	}
+	if f.Path != "" && event.Path != f.Path && !strings.HasPrefix(event.Path, strings.TrimSuffix(f.Path, "/")+"/") {
+		return false
+	}
+	return true
+}
+
+// Full reports whether events already hold as many as the filter wants
+func (f EventFilter) Full(events []ColorEvent) bool {
+	return f.Limit > 0 && len(events) >= f.Limit
+}
+
+// recordEvent adds an applied color to the history. A history that can't
+// be written never stops the color from being applied.
+func (c *ColorManager) recordEvent(change ColorChange, old *RGB16, color RGB16) {
+	if c.config.Persistence.HistoryLimit == 0 {
+		return
+	}
+
+	path := change.Path
+	if path == "" {
+		if cwd, err := os.Getwd(); err == nil {
+			path = cwd
+		}
+	}
+	if path != "" {
+		path = CanonicalPath(path)
+	}
+
+	event := ColorEvent{
+		Time:    time.Now().UTC(),
+		Source:  change.Source,
+		Mode:    change.Mode,
+		Path:    path,
+		Backend: c.config.Persistence.Backend,
+		Old:     old,
+		New:     color,
+		Command: change.Command,
+	}
+	if err := c.store.AddEvent(event); err != nil {
+		log.Printf("Error recording color event: %v", err)
+	}
+}
+
+// ColorHistory returns applied colors newest first
+func (c *ColorManager) ColorHistory(filter EventFilter) ([]ColorEvent, error) {
+	if filter.Path != "" {
+		filter.Path = CanonicalPath(filter.Path)
+	}
+	return c.store.GetEvents(filter)
+}
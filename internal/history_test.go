@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApplyColorRecordsAppliedColors(t *testing.T) {
+	fakeOsascript(t, fakeITerm)
+	c := NewColorManager(memoryConfig())
+	color := RGB{R: 0x12, G: 0x34, B: 0x56}
+
+	if err := c.ApplyColor(color, ColorChange{Source: "set"}); err != nil {
+		t.Fatal(err)
+	}
+	if last, ok := c.store.GetLastColor(); !ok || last != color.To16() {
+		t.Errorf("last color is %v, %v; want %v", last, ok, color.To16())
+	}
+	events, err := c.ColorHistory(EventFilter{})
+	if err != nil || len(events) != 1 || events[0].New != color.To16() || events[0].Source != "set" {
+		t.Errorf("history is %+v, %v", events, err)
+	}
+}
+
+func TestApplyColorSkipsFailedColors(t *testing.T) {
+	fakeOsascript(t, "exit 1")
+	c := NewColorManager(memoryConfig())
+
+	if err := c.ApplyColor(RGB{R: 0x12, G: 0x34, B: 0x56}, ColorChange{Source: "set"}); err == nil {
+		t.Fatal("a failed osascript wasn't reported")
+	}
+	if last, ok := c.store.GetLastColor(); ok {
+		t.Errorf("last color is %v though it was never applied", last)
+	}
+	if events, _ := c.ColorHistory(EventFilter{}); len(events) != 0 {
+		t.Errorf("history has %+v though nothing was applied", events)
+	}
+}
+
+func TestEventFilterMatch(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	event := ColorEvent{Time: now, Source: "directory", Path: "/work/api"}
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   bool
+	}{
+		{"empty filter", EventFilter{}, true},
+		{"same path", EventFilter{Path: "/work/api"}, true},
+		{"parent", EventFilter{Path: "/work"}, true},
+		{"parent with slash", EventFilter{Path: "/work/"}, true},
+		{"root", EventFilter{Path: "/"}, true},
+		{"sibling sharing a prefix", EventFilter{Path: "/work/ap"}, false},
+		{"child", EventFilter{Path: "/work/api/src"}, false},
+		{"other tree", EventFilter{Path: "/home"}, false},
+		{"source", EventFilter{Source: "directory"}, true},
+		{"other source", EventFilter{Source: "claude"}, false},
+		{"since before", EventFilter{Since: now.Add(-time.Hour)}, true},
+		{"since the moment", EventFilter{Since: now}, true},
+		{"since after", EventFilter{Since: now.Add(time.Second)}, false},
+		{"every field", EventFilter{Since: now.Add(-time.Hour), Source: "directory", Path: "/work"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(event); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps colors in memory for the life of the process, using
+// the same keys and lifetimes as Redis. It is meant for tests.
+type MemoryStore struct {
+	mu     sync.Mutex
+	config PersistenceConfig
+	values map[string]memoryValue
+	now    func() time.Time
+}
+
+// memoryValue is a stored JSON value and when it expires; zero means never
+type memoryValue struct {
+	Data    json.RawMessage `json:"data"`
+	Expires time.Time       `json:"expires,omitzero"`
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore(cfg PersistenceConfig) *MemoryStore {
+	return &MemoryStore{
+		config: cfg,
+		values: map[string]memoryValue{},
+		now:    time.Now,
+	}
+}
+
+// get returns a value that hasn't expired
+func (ms *MemoryStore) get(key string) (string, bool) {
+	value, ok := ms.values[key]
+	if !ok {
+		return "", false
+	}
+	if !value.Expires.IsZero() && !ms.now().Before(value.Expires) {
+		delete(ms.values, key)
+		return "", false
+	}
+	return string(value.Data), true
+}
+
+// set stores a JSON value, expiring after ttl unless it is 0
+func (ms *MemoryStore) set(key string, data string, ttl time.Duration) {
+	value := memoryValue{Data: json.RawMessage(data)}
+	if ttl > 0 {
+		value.Expires = ms.now().Add(ttl)
+	}
+	ms.values[key] = value
+}
+
+// setJSON marshals and stores a value
+func (ms *MemoryStore) setJSON(key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error marshaling %s: %w", key, err)
+	}
+	ms.set(key, string(data), ttl)
+	return nil
+}
+
+// getJSON unmarshals a stored value
+func (ms *MemoryStore) getJSON(key string, v interface{}) bool {
+	data, ok := ms.get(key)
+	return ok && json.Unmarshal([]byte(data), v) == nil
+}
+
+// ttl returns the remaining lifetime of a value, negative when it never
+// expires
+func (ms *MemoryStore) ttl(key string) time.Duration {
+	value := ms.values[key]
+	if value.Expires.IsZero() {
+		return -1
+	}
+	return value.Expires.Sub(ms.now())
+}
+
+// keys returns the live keys starting with prefix, sorted
+func (ms *MemoryStore) keys(prefix string) []string {
+	var keys []string
+	for key := range ms.values {
+		if strings.HasPrefix(key, prefix) {
+			if _, ok := ms.get(key); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetDirectoryColor retrieves stored color for a directory
+func (ms *MemoryStore) GetDirectoryColor(directoryPath string) (RGB16, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var entry ColorEntry
+	if !ms.getJSON("color:directory:"+directoryPath, &entry) {
+		return RGB16{}, false
+	}
+	return entry.Color16(), true
+}
+
+// SetDirectoryColor stores color for a directory, recording where it
+// came from
+func (ms *MemoryStore) SetDirectoryColor(directoryPath string, color RGB16, source string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.setJSON("color:directory:"+directoryPath, newColorEntry(color, source), directoryTTL(ms.config, source))
+}
+
+// CountDirectoryColors counts the directory colors that haven't expired
+func (ms *MemoryStore) CountDirectoryColors() (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return len(ms.keys("color:directory:")), nil
+}
+
+// GetRerollHistory retrieves the colors a directory had before each
+// reroll, oldest first
+func (ms *MemoryStore) GetRerollHistory(directoryPath string) []RGB16 {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var history []RGB16
+	ms.getJSON("color:reroll:"+directoryPath, &history)
+	return history
+}
+
+// SetRerollHistory stores the reroll history of a directory. Like
+// rerolled colors, it never expires.
+func (ms *MemoryStore) SetRerollHistory(directoryPath string, history []RGB16) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := "color:reroll:" + directoryPath
+	if len(history) == 0 {
+		delete(ms.values, key)
+		return nil
+	}
+	return ms.setJSON(key, history, 0)
+}
+
+// GetLastClaudeColor retrieves the last used Claude theme color
+func (ms *MemoryStore) GetLastClaudeColor() (RGB16, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var entry ColorEntry
+	if !ms.getJSON("color:claude:last", &entry) || ms.now().Sub(entry.Timestamp) > ms.config.ClaudeTTL {
+		return RGB16{}, false
+	}
+	return entry.Color16(), true
+}
+
+// SetLastClaudeColor stores the last used Claude theme color
+func (ms *MemoryStore) SetLastClaudeColor(color RGB16) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.setJSON("color:claude:last", newColorEntry(color, "claude"), ms.config.ClaudeTTL)
+}
+
+// GetCycleState retrieves the harmony walk state of `color cycle`
+func (ms *MemoryStore) GetCycleState() (CycleState, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var state CycleState
+	ok := ms.getJSON("color:cycle:state", &state)
+	return state, ok
+}
+
+// SetCycleState stores the harmony walk state of `color cycle`
+func (ms *MemoryStore) SetCycleState(state CycleState) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.setJSON("color:cycle:state", state, ms.config.CycleTTL)
+}
+
+// GetLastColor retrieves the last color applied to the terminal
+func (ms *MemoryStore) GetLastColor() (RGB16, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var entry ColorEntry
+	if !ms.getJSON("color:last", &entry) {
+		return RGB16{}, false
+	}
+	return entry.Color16(), true
+}
+
+// SetLastColor stores the last color applied to the terminal
+func (ms *MemoryStore) SetLastColor(color RGB16) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.setJSON("color:last", newColorEntry(color, "applied"), ms.config.DirectoryTTL)
+}
+
+// AddEvent records an applied color, keeping the newest
+// persistence.history_limit events
+func (ms *MemoryStore) AddEvent(event ColorEvent) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var events []ColorEvent
+	ms.getJSON("color:events", &events)
+	events = append(events, event)
+	if limit := ms.config.HistoryLimit; len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return ms.setJSON("color:events", events, 0)
+}
+
+// GetEvents retrieves the events matching filter, newest first
+func (ms *MemoryStore) GetEvents(filter EventFilter) ([]ColorEvent, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var events, matched []ColorEvent
+	ms.getJSON("color:events", &events)
+	for i := len(events) - 1; i >= 0 && !filter.Full(matched); i-- {
+		if filter.Match(events[i]) {
+			matched = append(matched, events[i])
+		}
+	}
+	return matched, nil
+}
+
+// SetPin pins a directory color. Pins never expire.
+func (ms *MemoryStore) SetPin(directoryPath string, color RGB16, subtree bool) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	entry := newColorEntry(color, "pinned")
+	entry.Subtree = subtree
+	return ms.setJSON("color:pin:"+directoryPath, entry, 0)
+}
+
+// GetPin retrieves the pin covering a directory: its own, or the nearest
+// subtree pin of an ancestor
+func (ms *MemoryStore) GetPin(directoryPath string) (Pin, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.pin(directoryPath)
+}
+
+// pin is GetPin without locking
+func (ms *MemoryStore) pin(directoryPath string) (Pin, bool) {
+	for dir := directoryPath; ; dir = path.Dir(dir) {
+		var entry ColorEntry
+		if ms.getJSON("color:pin:"+dir, &entry) && (dir == directoryPath || entry.Subtree) {
+			return Pin{Path: dir, Entry: entry}, true
+		}
+		if dir == path.Dir(dir) {
+			return Pin{}, false
+		}
+	}
+}
+
+// DeletePin removes a directory's own pin, reporting whether it had one
+func (ms *MemoryStore) DeletePin(directoryPath string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := "color:pin:" + directoryPath
+	_, ok := ms.get(key)
+	delete(ms.values, key)
+	return ok, nil
+}
+
+// ListPins retrieves every pinned directory sorted by path
+func (ms *MemoryStore) ListPins() ([]Pin, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var pins []Pin
+	for _, key := range ms.keys("color:pin:") {
+		var entry ColorEntry
+		if ms.getJSON(key, &entry) {
+			pins = append(pins, Pin{Path: strings.TrimPrefix(key, "color:pin:"), Entry: entry})
+		}
+	}
+	return pins, nil
+}
+
+// GetTheme retrieves a saved theme
+func (ms *MemoryStore) GetTheme(name string) (Theme, bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	data, ok := ms.get("color:theme:" + name)
+	if !ok {
+		return Theme{}, false, nil
+	}
+	var theme Theme
+	if err := json.Unmarshal([]byte(data), &theme); err != nil {
+		return Theme{}, false, fmt.Errorf("error unmarshaling theme %s: %w", name, err)
+	}
+	return theme, true, nil
+}
+
+// SetTheme saves a theme. Themes never expire.
+func (ms *MemoryStore) SetTheme(theme Theme) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.setJSON("color:theme:"+theme.Name, theme, 0)
+}
+
+// ListThemes retrieves every saved theme sorted by name
+func (ms *MemoryStore) ListThemes() ([]Theme, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var themes []Theme
+	for _, key := range ms.keys("color:theme:") {
+		var theme Theme
+		if ms.getJSON(key, &theme) {
+			themes = append(themes, theme)
+		}
+	}
+	return themes, nil
+}
+
+// DeleteTheme removes a saved theme, reporting whether it existed
+func (ms *MemoryStore) DeleteTheme(name string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	key := "color:theme:" + name
+	_, ok := ms.get(key)
+	delete(ms.values, key)
+	return ok, nil
+}
+
+// ClearColorCache removes all stored colors. Saved themes are kept, and so
+// are pinned directory colors unless all is set.
+func (ms *MemoryStore) ClearColorCache(all bool) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for key := range ms.values {
+		pinned := strings.HasPrefix(key, "color:pin:") && !all
+		if !strings.HasPrefix(key, "color:theme:") && !pinned {
+			delete(ms.values, key)
+		}
+	}
+	return nil
+}
+
+// MigrateDirectoryKeys rewrites every directory key to the key
+// canonical returns for its path, merging collisions like the Redis store
+func (ms *MemoryStore) MigrateDirectoryKeys(canonical func(path string) (string, bool), force bool) (MigrationResult, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var result MigrationResult
+	if _, done := ms.get("color:" + canonicalMigrationKey); done && !force {
+		result.AlreadyDone = true
+		return result, nil
+	}
+
+	groups := map[string][]storedEntry{}
+	for _, key := range ms.keys("color:directory:") {
+		path := strings.TrimPrefix(key, "color:directory:")
+		target, ok := canonical(path)
+		if !ok {
+			result.Skipped = append(result.Skipped, path)
+			continue
+		}
+		data, _ := ms.get(key)
+		var entry ColorEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			continue
+		}
+		target = "color:directory:" + target
+		groups[target] = append(groups[target], storedEntry{key, data, entry, ms.ttl(key)})
+	}
+
+	for target, group := range groups {
+		if len(group) == 1 && group[0].key == target {
+			continue
+		}
+		winner, ttl := migrationWinner(group)
+		ms.set(target, winner.data, ttl)
+		for _, s := range group {
+			if s.key == target {
+				continue
+			}
+			delete(ms.values, s.key)
+			if s.key == winner.key {
+				result.Rewritten++
+			} else {
+				result.Merged++
+			}
+		}
+	}
+
+	return result, ms.setJSON("color:"+canonicalMigrationKey, ms.now().Format(time.RFC3339), 0)
+}
+
+// GetConnectionStatus describes the memory store
+func (ms *MemoryStore) GetConnectionStatus() string {
+	return "⚠️ In-memory store (colors last for this process only)"
+}
+
+// Close does nothing; the colors go with the process
+func (ms *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,121 @@
+package internal
+
+import "math"
+
+// OKLab represents a color in the OKLab perceptual color space
+type OKLab struct {
+	L, A, B float64
+}
+
+// OKLCH represents OKLab in cylindrical form (lightness, chroma, hue in radians)
+type OKLCH struct {
+	L, C, H float64
+}
+
+// srgbToLinear converts an sRGB channel (0-1) to linear light
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB converts a linear light channel to sRGB (0-1)
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// RGBToOKLab converts RGB to OKLab
+func RGBToOKLab(rgb RGB) OKLab {
+	return RGB16ToOKLab(rgb.To16())
+}
+
+// RGB16ToOKLab converts 16-bit RGB to OKLab
+func RGB16ToOKLab(rgb RGB16) OKLab {
+	r := srgbToLinear(float64(rgb.R) / 65535.0)
+	g := srgbToLinear(float64(rgb.G) / 65535.0)
+	b := srgbToLinear(float64(rgb.B) / 65535.0)
+
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+
+	return OKLab{
+		L: 0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		A: 1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		B: 0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+// OKLabToRGB converts OKLab to RGB, clamping out-of-gamut values
+func OKLabToRGB(lab OKLab) RGB {
+	return OKLabToRGB16(lab).To8()
+}
+
+// OKLabToRGB16 converts OKLab to 16-bit RGB, clamping out-of-gamut values
+func OKLabToRGB16(lab OKLab) RGB16 {
+	l := lab.L + 0.3963377774*lab.A + 0.2158037573*lab.B
+	m := lab.L - 0.1055613458*lab.A - 0.0638541728*lab.B
+	s := lab.L - 0.0894841775*lab.A - 1.2914855480*lab.B
+
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	r := 4.0767416621*l - 3.3077115913*m + 0.2309699292*s
+	g := -1.2684380046*l + 2.6097574011*m - 0.3413193965*s
+	b := -0.0041960863*l - 0.7034186147*m + 1.7076147010*s
+
+	return RGB16{
+		R: toChannel16(linearToSRGB(r)),
+		G: toChannel16(linearToSRGB(g)),
+		B: toChannel16(linearToSRGB(b)),
+	}
+}
+
+// ToOKLCH converts OKLab to its cylindrical form
+func (lab OKLab) ToOKLCH() OKLCH {
+	return OKLCH{
+		L: lab.L,
+		C: math.Hypot(lab.A, lab.B),
+		H: math.Atan2(lab.B, lab.A),
+	}
+}
+
+// ToOKLab converts OKLCH back to OKLab
+func (lch OKLCH) ToOKLab() OKLab {
+	return OKLab{
+		L: lch.L,
+		A: lch.C * math.Cos(lch.H),
+		B: lch.C * math.Sin(lch.H),
+	}
+}
+
+// LerpOKLab interpolates between two colors in OKLab space (t in 0-1)
+func LerpOKLab(from, to RGB, t float64) RGB {
+	return LerpOKLab16(from.To16(), to.To16(), t).To8()
+}
+
+// LerpOKLab16 is LerpOKLab at full precision
+func LerpOKLab16(from, to RGB16, t float64) RGB16 {
+	a, b := RGB16ToOKLab(from), RGB16ToOKLab(to)
+	return OKLabToRGB16(OKLab{
+		L: a.L + (b.L-a.L)*t,
+		A: a.A + (b.A-a.A)*t,
+		B: a.B + (b.B-a.B)*t,
+	})
+}
+
+// DistanceOKLab returns the perceptual distance between two colors, the
+// Euclidean distance in OKLab
+func DistanceOKLab(a, b RGB16) float64 {
+	x, y := RGB16ToOKLab(a), RGB16ToOKLab(b)
+	return math.Sqrt((x.L-y.L)*(x.L-y.L) + (x.A-y.A)*(x.A-y.A) + (x.B-y.B)*(x.B-y.B))
+}
+
+// toChannel16 converts a 0-1 value to a rounded 0-65535 channel
+func toChannel16(v float64) uint16 {
+	v = math.Max(0, math.Min(1, v))
+	return uint16(math.Round(v * 65535))
+}
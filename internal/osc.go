@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ttyPath is the controlling terminal escape sequences are written to
+var ttyPath = "/dev/tty"
+
+// remoteSession reports whether the shell runs in an SSH session, where
+// osascript would reach the remote Mac, if any, rather than the local
+// iTerm2. Colors are set with escape sequences the terminal interprets
+// instead.
+func remoteSession() bool {
+	return os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != ""
+}
+
+// oscColor formats an xterm color-setting sequence: code 10 is the
+// foreground, 11 the background, 12 the cursor, 17 and 19 the selection
+// background and text, and "4;N" ANSI color N
+func oscColor(code string, color RGB16) string {
+	return fmt.Sprintf("\x1b]%s;rgb:%04x/%04x/%04x\x07", code, color.R, color.G, color.B)
+}
+
+// writeOSC sends escape sequences to the controlling terminal in one write
+func writeOSC(sequences ...string) error {
+	tty, err := os.OpenFile(ttyPath, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("can't open the terminal: %w", err)
+	}
+	defer tty.Close()
+	_, err = tty.WriteString(strings.Join(sequences, ""))
+	return err
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseColor parses a color written as "#rrggbb", "#rgb", "rgb(r, g, b)" or
+// "r,g,b" with 0-255 channels
+func ParseColor(text string) (RGB, error) {
+	color, err := ParseColor16(text)
+	return color.To8(), err
+}
+
+// ParseColor16 parses any format accepted by ParseColor, plus 16-bit
+// "#rrrrggggbbbb", keeping full precision
+func ParseColor16(text string) (RGB16, error) {
+	s := strings.ToLower(strings.TrimSpace(text))
+
+	if strings.HasPrefix(s, "#") && len(s) == 13 {
+		value, err := strconv.ParseUint(s[1:], 16, 64)
+		if err != nil {
+			return RGB16{}, fmt.Errorf("invalid hex color %q", text)
+		}
+		return RGB16{R: uint16(value >> 32), G: uint16(value >> 16), B: uint16(value)}, nil
+	}
+
+	color, err := parseColor8(s, text)
+	return color.To16(), err
+}
+
+// parseColor8 parses the 8-bit color formats
+func parseColor8(s, text string) (RGB, error) {
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return RGB{}, fmt.Errorf("invalid hex color %q", text)
+		}
+		value, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return RGB{}, fmt.Errorf("invalid hex color %q", text)
+		}
+		return RGB{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value)}, nil
+	}
+
+	if strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")") {
+		s = s[4 : len(s)-1]
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return RGB{}, fmt.Errorf("invalid color %q (expected #rrggbb or r,g,b)", text)
+	}
+
+	var values [3]uint8
+	for i, part := range parts {
+		v, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+		if err != nil {
+			return RGB{}, fmt.Errorf("invalid color channel %q in %q", strings.TrimSpace(part), text)
+		}
+		values[i] = uint8(v)
+	}
+
+	return RGB{R: values[0], G: values[1], B: values[2]}, nil
+}
+
+// Hex formats the color as "#rrggbb"
+func (rgb RGB) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", rgb.R, rgb.G, rgb.B)
+}
+
+// Hex formats the color as "#rrggbb" when it is exactly representable in 8
+// bits, and as "#rrrrggggbbbb" otherwise
+func (rgb RGB16) Hex() string {
+	if rgb.To8().To16() == rgb {
+		return rgb.To8().Hex()
+	}
+	return fmt.Sprintf("#%04x%04x%04x", rgb.R, rgb.G, rgb.B)
+}
@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// CanonicalPath expands a leading ~, makes path absolute, cleans it and
+// resolves symlinks, so every spelling of a directory shares one color.
+// Paths that don't exist are only cleaned.
+func CanonicalPath(path string) string {
+	abs, err := filepath.Abs(expandHome(path))
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+// pathKey returns the string a canonical path is hashed and stored under,
+// folding case when configured for case-insensitive file systems
+func (c *ColorManager) pathKey(path string) string {
+	if c.config.Paths.FoldCase {
+		return strings.ToLower(path)
+	}
+	return path
+}
+
+// MigrateDirectoryKeys merges stored directory colors whose paths
+// canonicalize to the same key. It runs once unless forced.
+func (c *ColorManager) MigrateDirectoryKeys(force bool) (MigrationResult, error) {
+	return c.store.MigrateDirectoryKeys(func(path string) (string, bool) {
+		// Relative keys can't be resolved without the directory they came from
+		if !filepath.IsAbs(expandHome(path)) {
+			return "", false
+		}
+		return c.pathKey(CanonicalPath(path)), true
+	}, force)
+}
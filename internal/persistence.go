@@ -1,248 +1,232 @@
 package internal
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
-
-	"github.com/redis/go-redis/v9"
 )
 
-// PersistenceManager handles color persistence using Redis
-type PersistenceManager struct {
-	client *redis.Client
-	ctx    context.Context
+// Store persists colors between invocations: directory colors, session
+// colors, history, pins and themes
+type Store interface {
+	// Directory colors
+	GetDirectoryColor(directoryPath string) (RGB16, bool)
+	SetDirectoryColor(directoryPath string, color RGB16, source string) error
+	GetRerollHistory(directoryPath string) []RGB16
+	SetRerollHistory(directoryPath string, history []RGB16) error
+	CountDirectoryColors() (int, error)
+	MigrateDirectoryKeys(canonical func(path string) (string, bool), force bool) (MigrationResult, error)
+
+	// Session colors
+	GetLastClaudeColor() (RGB16, bool)
+	SetLastClaudeColor(color RGB16) error
+	GetCycleState() (CycleState, bool)
+	SetCycleState(state CycleState) error
+	GetLastColor() (RGB16, bool)
+	SetLastColor(color RGB16) error
+
+	// History of applied colors, newest first
+	AddEvent(event ColorEvent) error
+	GetEvents(filter EventFilter) ([]ColorEvent, error)
+
+	// Pins
+	SetPin(directoryPath string, color RGB16, subtree bool) error
+	GetPin(directoryPath string) (Pin, bool)
+	DeletePin(directoryPath string) (bool, error)
+	ListPins() ([]Pin, error)
+
+	// Themes
+	GetTheme(name string) (Theme, bool, error)
+	SetTheme(theme Theme) error
+	ListThemes() ([]Theme, error)
+	DeleteTheme(name string) (bool, error)
+
+	ClearColorCache(all bool) error
+	GetConnectionStatus() string
+	Close() error
 }
 
-// ColorEntry represents a stored color with metadata
-type ColorEntry struct {
-	Color     RGB       `json:"color"`
-	Timestamp time.Time `json:"timestamp"`
-	Source    string    `json:"source"` // "directory", "claude", "manual"
-}
+var (
+	_ Store = (*RedisStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*FileStore)(nil)
+	_ Store = NopStore{}
+)
 
-// NewPersistenceManager creates a new persistence manager
-func NewPersistenceManager() *PersistenceManager {
-	// Try to connect to Redis with common configurations
-	addresses := []string{
-		"localhost:6379", // Default Redis port
-		"127.0.0.1:6379", // Alternative localhost
-		"redis:6379",     // Docker container name
-	}
+// StoreBackends lists the persistence backends
+var StoreBackends = []string{"file", "redis", "memory", "none"}
 
-	var client *redis.Client
-	ctx := context.Background()
-
-	for _, addr := range addresses {
-		client = redis.NewClient(&redis.Options{
-			Addr:         addr,
-			Password:     "", // No password by default
-			DB:           0,  // Default DB
-			DialTimeout:  time.Second * 2,
-			ReadTimeout:  time.Second * 2,
-			WriteTimeout: time.Second * 2,
-		})
-
-		// Test connection
-		_, err := client.Ping(ctx).Result()
-		if err == nil {
-			break
+// ParseBackend validates a persistence backend name
+func ParseBackend(name string) (string, error) {
+	for _, backend := range StoreBackends {
+		if name == backend {
+			return name, nil
 		}
-		client.Close()
-		client = nil
-	}
-
-	return &PersistenceManager{
-		client: client,
-		ctx:    ctx,
 	}
+	return "", fmt.Errorf("unknown persistence backend %q (expected %s)", name, strings.Join(StoreBackends, ", "))
 }
 
-// IsEnabled returns true if Redis connection is available
-func (pm *PersistenceManager) IsEnabled() bool {
-	return pm.client != nil
-}
-
-// GetDirectoryColor retrieves stored color for a directory
-func (pm *PersistenceManager) GetDirectoryColor(directoryPath string) (RGB, bool) {
-	if !pm.IsEnabled() {
-		return RGB{}, false
+// NewStore opens the configured store, falling back to a store that keeps
+// nothing when it is unavailable
+func NewStore(cfg PersistenceConfig) Store {
+	switch cfg.Backend {
+	case "redis":
+		store, err := NewRedisStore(cfg)
+		if err != nil {
+			log.Printf("Colors won't persist: %v", err)
+			return NopStore{Reason: err.Error()}
+		}
+		return store
+	case "memory":
+		return NewMemoryStore(cfg)
+	case "none":
+		return NopStore{Reason: "persistence is off"}
 	}
 
-	key := fmt.Sprintf("color:directory:%s", directoryPath)
-	data, err := pm.client.Get(pm.ctx, key).Result()
-	if err == redis.Nil {
-		return RGB{}, false // Key doesn't exist
+	dir := cfg.Path
+	if dir == "" {
+		dir = StateDir()
 	}
+	store, err := NewFileStore(expandHome(dir), cfg)
 	if err != nil {
-		log.Printf("Redis error getting directory color: %v", err)
-		return RGB{}, false
+		return NopStore{Reason: err.Error()}
 	}
-
-	var entry ColorEntry
-	if err := json.Unmarshal([]byte(data), &entry); err != nil {
-		log.Printf("Error unmarshaling color entry: %v", err)
-		return RGB{}, false
-	}
-
-	return entry.Color, true
+	return store
 }
 
-// SetDirectoryColor stores color for a directory
-func (pm *PersistenceManager) SetDirectoryColor(directoryPath string, color RGB) error {
-	if !pm.IsEnabled() {
-		return nil // Fail silently if Redis unavailable
-	}
-
-	entry := ColorEntry{
-		Color:     color,
-		Timestamp: time.Now(),
-		Source:    "directory",
-	}
-
-	data, err := json.Marshal(entry)
-	if err != nil {
-		return fmt.Errorf("error marshaling color entry: %w", err)
-	}
-
-	key := fmt.Sprintf("color:directory:%s", directoryPath)
-	// Set with 30 day expiration to prevent infinite growth
-	err = pm.client.Set(pm.ctx, key, data, time.Hour*24*30).Err()
-	if err != nil {
-		log.Printf("Redis error setting directory color: %v", err)
-	}
-
-	return err
+// ColorEntry represents a stored color with metadata
+type ColorEntry struct {
+	Color     RGB       `json:"color"`
+	Precise   *RGB16    `json:"precise,omitempty"` // Full precision; absent in older entries
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`            // "directory", "claude", "manual", "pinned", "reroll"
+	Subtree   bool      `json:"subtree,omitempty"` // A pin that covers every directory below
 }
 
-// GetLastClaudeColor retrieves the last used Claude theme color
-func (pm *PersistenceManager) GetLastClaudeColor() (RGB, bool) {
-	if !pm.IsEnabled() {
-		return RGB{}, false
-	}
-
-	key := "color:claude:last"
-	data, err := pm.client.Get(pm.ctx, key).Result()
-	if err == redis.Nil {
-		return RGB{}, false
-	}
-	if err != nil {
-		log.Printf("Redis error getting Claude color: %v", err)
-		return RGB{}, false
-	}
-
-	var entry ColorEntry
-	if err := json.Unmarshal([]byte(data), &entry); err != nil {
-		log.Printf("Error unmarshaling Claude color entry: %v", err)
-		return RGB{}, false
-	}
-
-	// Only return if it's recent (within 24 hours)
-	if time.Since(entry.Timestamp) > time.Hour*24 {
-		return RGB{}, false
-	}
-
-	return entry.Color, true
+// Pin is a pinned directory color
+type Pin struct {
+	Path  string
+	Entry ColorEntry
 }
 
-// SetLastClaudeColor stores the last used Claude theme color
-func (pm *PersistenceManager) SetLastClaudeColor(color RGB) error {
-	if !pm.IsEnabled() {
-		return nil
-	}
-
-	entry := ColorEntry{
-		Color:     color,
+// newColorEntry creates an entry keeping both the rounded and precise color
+func newColorEntry(color RGB16, source string) ColorEntry {
+	return ColorEntry{
+		Color:     color.To8(),
+		Precise:   &color,
 		Timestamp: time.Now(),
-		Source:    "claude",
+		Source:    source,
 	}
+}
 
-	data, err := json.Marshal(entry)
-	if err != nil {
-		return fmt.Errorf("error marshaling Claude color entry: %w", err)
+// Color16 returns the entry's color at full precision
+func (e ColorEntry) Color16() RGB16 {
+	if e.Precise != nil {
+		return *e.Precise
 	}
+	return e.Color.To16()
+}
 
-	key := "color:claude:last"
-	// Claude colors expire after 24 hours to allow theme variation
-	err = pm.client.Set(pm.ctx, key, data, time.Hour*24).Err()
-	if err != nil {
-		log.Printf("Redis error setting Claude color: %v", err)
-	}
+// CycleState remembers where `color cycle` is within a harmony set
+type CycleState struct {
+	Base  RGB16  `json:"base16"`
+	Mode  string `json:"mode"`
+	Index int    `json:"index"`
+	Last  RGB16  `json:"last16"`
+}
 
-	return err
+// MigrationResult summarizes a directory key migration
+type MigrationResult struct {
+	AlreadyDone bool     // The migration ran before and wasn't forced
+	Rewritten   int      // Keys moved to their canonical path
+	Merged      int      // Keys dropped because another key won the merge
+	Skipped     []string // Paths that can't be canonicalized
 }
 
-// GetColorHistory retrieves recent color history
-func (pm *PersistenceManager) GetColorHistory(limit int) ([]ColorEntry, error) {
-	if !pm.IsEnabled() {
-		return []ColorEntry{}, nil
-	}
+// canonicalMigrationKey marks the canonical path migration as done, below
+// the store's key prefix
+const canonicalMigrationKey = "migrations:canonical-paths"
+
+// storedEntry is a stored directory color with its key and remaining
+// lifetime, negative when it never expires
+type storedEntry struct {
+	key   string
+	data  string
+	entry ColorEntry
+	ttl   time.Duration
+}
 
-	// Get all directory color keys
-	keys, err := pm.client.Keys(pm.ctx, "color:directory:*").Result()
-	if err != nil {
-		return []ColorEntry{}, err
+// directoryTTL returns how long a directory color from source is kept.
+// Rerolled colors were chosen by hand, so they and their history don't
+// expire.
+func directoryTTL(config PersistenceConfig, source string) time.Duration {
+	if source == "reroll" {
+		return 0
 	}
+	return config.DirectoryTTL
+}
 
-	var entries []ColorEntry
-	for _, key := range keys {
-		data, err := pm.client.Get(pm.ctx, key).Result()
-		if err != nil {
-			continue
-		}
-
-		var entry ColorEntry
-		if err := json.Unmarshal([]byte(data), &entry); err != nil {
-			continue
+// migrationWinner picks the entry that survives when several keys merge
+// into one: the latest entry wins, keeping the longest remaining
+// lifetime, 0 for none.
+func migrationWinner(group []storedEntry) (storedEntry, time.Duration) {
+	winner, ttl := group[0], group[0].ttl
+	for _, s := range group[1:] {
+		if s.entry.Timestamp.After(winner.entry.Timestamp) {
+			winner = s
 		}
-
-		entries = append(entries, entry)
-		if len(entries) >= limit {
-			break
+		if ttl >= 0 && (s.ttl < 0 || s.ttl > ttl) {
+			ttl = s.ttl
 		}
 	}
-
-	return entries, nil
-}
-
-// ClearColorCache removes all stored colors
-func (pm *PersistenceManager) ClearColorCache() error {
-	if !pm.IsEnabled() {
-		return nil
-	}
-
-	keys, err := pm.client.Keys(pm.ctx, "color:*").Result()
-	if err != nil {
-		return err
-	}
-
-	if len(keys) > 0 {
-		return pm.client.Del(pm.ctx, keys...).Err()
+	if ttl < 0 {
+		ttl = 0
 	}
-
-	return nil
+	return winner, ttl
 }
 
-// GetConnectionStatus returns Redis connection status
-func (pm *PersistenceManager) GetConnectionStatus() string {
-	if !pm.IsEnabled() {
-		return "❌ Redis unavailable (colors won't persist)"
-	}
+// NopStore keeps nothing. It stands in when no store is available, so
+// callers never check for one.
+type NopStore struct {
+	Reason string // Why nothing is stored, e.g. "Redis unavailable"
+}
 
-	_, err := pm.client.Ping(pm.ctx).Result()
-	if err != nil {
-		return "⚠️ Redis connection issues"
-	}
+// err explains why nothing was stored or found
+func (n NopStore) err() error {
+	return fmt.Errorf("colors aren't stored: %s", n.Reason)
+}
 
-	return "✅ Redis connected (colors will persist)"
+func (n NopStore) GetDirectoryColor(string) (RGB16, bool)        { return RGB16{}, false }
+func (n NopStore) SetDirectoryColor(string, RGB16, string) error { return n.err() }
+func (n NopStore) GetRerollHistory(string) []RGB16               { return nil }
+func (n NopStore) SetRerollHistory(string, []RGB16) error        { return n.err() }
+func (n NopStore) CountDirectoryColors() (int, error)            { return 0, n.err() }
+func (n NopStore) GetLastClaudeColor() (RGB16, bool)             { return RGB16{}, false }
+func (n NopStore) SetLastClaudeColor(RGB16) error                { return n.err() }
+func (n NopStore) GetCycleState() (CycleState, bool)             { return CycleState{}, false }
+func (n NopStore) SetCycleState(CycleState) error                { return n.err() }
+func (n NopStore) GetLastColor() (RGB16, bool)                   { return RGB16{}, false }
+func (n NopStore) SetLastColor(RGB16) error                      { return n.err() }
+func (n NopStore) AddEvent(ColorEvent) error                     { return nil }
+func (n NopStore) GetEvents(EventFilter) ([]ColorEvent, error)   { return nil, n.err() }
+func (n NopStore) SetPin(string, RGB16, bool) error              { return n.err() }
+func (n NopStore) GetPin(string) (Pin, bool)                     { return Pin{}, false }
+func (n NopStore) DeletePin(string) (bool, error)                { return false, n.err() }
+func (n NopStore) ListPins() ([]Pin, error)                      { return nil, n.err() }
+func (n NopStore) GetTheme(string) (Theme, bool, error)          { return Theme{}, false, n.err() }
+func (n NopStore) SetTheme(Theme) error                          { return n.err() }
+func (n NopStore) ListThemes() ([]Theme, error)                  { return nil, n.err() }
+func (n NopStore) DeleteTheme(string) (bool, error)              { return false, n.err() }
+func (n NopStore) ClearColorCache(bool) error                    { return nil }
+func (n NopStore) Close() error                                  { return nil }
+
+// MigrateDirectoryKeys has nothing to migrate
+func (n NopStore) MigrateDirectoryKeys(func(string) (string, bool), bool) (MigrationResult, error) {
+	return MigrationResult{}, nil
 }
 
-// Close closes the Redis connection
-func (pm *PersistenceManager) Close() error {
-	if pm.client != nil {
-		return pm.client.Close()
-	}
-	return nil
-}
\ No newline at end of file
+// GetConnectionStatus explains why colors won't persist
+func (n NopStore) GetConnectionStatus() string {
+	return fmt.Sprintf("❌ %s (colors won't persist)", n.Reason)
+}
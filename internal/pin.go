@@ -0,0 +1,50 @@
+package internal
+
+// PinFor returns the pin covering a directory, either its own or a subtree
+// pin of an ancestor
+func (c *ColorManager) PinFor(directoryPath string) (Pin, bool) {
+	return c.store.GetPin(c.pathKey(CanonicalPath(directoryPath)))
+}
+
+// PinDirectory pins a directory's color so it never expires. Colors are
+// given in the dark appearance, like .colorrc colors; without one the
+// directory's current color is pinned. With subtree set, the pin also
+// covers every directory below.
+func (c *ColorManager) PinDirectory(directoryPath string, color *RGB16, subtree bool) (RGB16, error) {
+	directoryPath = CanonicalPath(directoryPath)
+
+	if color == nil {
+		current := c.currentBaseColor(directoryPath)
+		color = &current
+	}
+	return *color, c.store.SetPin(c.pathKey(directoryPath), *color, subtree)
+}
+
+// UnpinDirectory removes a directory's own pin, reporting whether it had one
+func (c *ColorManager) UnpinDirectory(directoryPath string) (bool, error) {
+	return c.store.DeletePin(c.pathKey(CanonicalPath(directoryPath)))
+}
+
+// ListPins returns every pinned directory sorted by path
+func (c *ColorManager) ListPins() ([]Pin, error) {
+	return c.store.ListPins()
+}
+
+// currentBaseColor returns the color a directory has now in the dark
+// appearance, before branch tints, SSH blending and schedules
+func (c *ColorManager) currentBaseColor(directoryPath string) RGB16 {
+	if pin, ok := c.PinFor(directoryPath); ok {
+		return pin.Entry.Color16()
+	}
+
+	settings := c.DirectorySettings(directoryPath)
+	if settings.Theme != nil {
+		if theme, err := c.LoadTheme(*settings.Theme); err == nil {
+			return theme.Background
+		}
+	}
+	if settings.Color != nil {
+		return settings.Color.To16()
+	}
+	return c.generatedColor(directoryPath, settings)
+}
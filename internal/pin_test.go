@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// gitProject creates a repository root with a src directory below it
+func gitProject(t *testing.T) (root, src string) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir()) // Empty trust store
+	root = CanonicalPath(t.TempDir())
+	src = filepath.Join(root, "src")
+	for _, dir := range []string{filepath.Join(root, ".git"), src} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root, src
+}
+
+func TestPinWithoutSubtreeColorsOnlyItsDirectory(t *testing.T) {
+	root, src := gitProject(t)
+	c := NewColorManager(memoryConfig())
+	color := RGB{R: 0x01, G: 0x02, B: 0x03}.To16()
+	if _, err := c.PinDirectory(root, &color, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.currentBaseColor(root); got != color {
+		t.Errorf("%s is %v, want its pin %v", root, got, color)
+	}
+	if _, ok := c.PinFor(src); ok {
+		t.Errorf("%s is covered by a pin without --subtree", src)
+	}
+	if got := c.currentBaseColor(src); got == color {
+		t.Errorf("%s has the color of its repository's pin without --subtree", src)
+	}
+}
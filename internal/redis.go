@@ -0,0 +1,777 @@
+package internal
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scanCount is how many keys each SCAN step asks Redis to look at
+const scanCount = 500
+
+// indexPage is how many directories each ZRANGE of the index reads
+const indexPage = 500
+
+// RedisStore handles color persistence using Redis. Keys are only ever
+// listed with SCAN, never KEYS, so a large shared database isn't blocked.
+type RedisStore struct {
+	client *redis.Client
+	ctx    context.Context
+	config PersistenceConfig
+	prefix string // Prepended to every key, "color:" by default
+}
+
+// NewRedisStore connects to the configured Redis URL, or else to the first
+// reachable address. The error names every address that failed and why.
+func NewRedisStore(cfg PersistenceConfig) (*RedisStore, error) {
+	candidates, err := redisOptions(cfg.Redis)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	var failures []string
+	for _, opts := range candidates {
+		client := redis.NewClient(opts)
+
+		// Test connection
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			failures = append(failures, fmt.Sprintf("%s: %v", opts.Addr, err))
+			continue
+		}
+		return &RedisStore{
+			client: client,
+			ctx:    ctx,
+			config: cfg,
+			prefix: cfg.Redis.Prefix,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("can't connect to Redis (%s)", strings.Join(failures, "; "))
+}
+
+// ParseRedisURL parses a redis://, rediss:// or unix:// URL with optional
+// credentials and database number
+func ParseRedisURL(url string) (*redis.Options, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		// Errors may quote the URL; keep any password out of them
+		return nil, fmt.Errorf("invalid Redis URL: %v", strings.ReplaceAll(err.Error(), url, "<url>"))
+	}
+	return opts, nil
+}
+
+// redisOptions returns the connections to try, in order: the URL when one
+// is configured, otherwise every address
+func redisOptions(cfg RedisConfig) ([]*redis.Options, error) {
+	if cfg.URL == "" {
+		var candidates []*redis.Options
+		for _, addr := range cfg.Addresses {
+			candidates = append(candidates, &redis.Options{
+				Addr:         addr,
+				DialTimeout:  cfg.Timeout,
+				ReadTimeout:  cfg.Timeout,
+				WriteTimeout: cfg.Timeout,
+			})
+		}
+		return candidates, nil
+	}
+
+	opts, err := ParseRedisURL(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	opts.DialTimeout = cfg.Timeout
+	opts.ReadTimeout = cfg.Timeout
+	opts.WriteTimeout = cfg.Timeout
+
+	if cfg.TLSCAFile != "" || cfg.TLSInsecure {
+		if opts.TLSConfig == nil {
+			return nil, fmt.Errorf("Redis TLS settings need a rediss:// URL")
+		}
+		opts.TLSConfig.InsecureSkipVerify = cfg.TLSInsecure
+	}
+	if cfg.TLSCAFile != "" {
+		data, err := os.ReadFile(expandHome(cfg.TLSCAFile))
+		if err != nil {
+			return nil, fmt.Errorf("can't read Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificates in Redis CA file %s", cfg.TLSCAFile)
+		}
+		opts.TLSConfig.RootCAs = pool
+	}
+
+	return []*redis.Options{opts}, nil
+}
+
+// indexKey returns the sorted set of directory paths scored by when their
+// color was last used
+func (rs *RedisStore) indexKey() string {
+	return rs.prefix + "index:directories"
+}
+
+// scan calls fn with each page of keys starting with prefix. A key may be
+// passed more than once, as SCAN allows.
+func (rs *RedisStore) scan(prefix string, fn func(keys []string) error) error {
+	pattern := escapeMatch(prefix) + "*"
+	var cursor uint64
+	for {
+		keys, next, err := rs.client.Scan(rs.ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// directories calls fn with each page of indexed directory paths whose
+// colors are still stored, least recently used first, and their entries.
+// Paths whose colors have expired are dropped from the index on the way,
+// which keeps it from growing without bound.
+func (rs *RedisStore) directories(fn func(paths, entries []string) error) error {
+	for start := int64(0); ; {
+		paths, err := rs.client.ZRange(rs.ctx, rs.indexKey(), start, start+indexPage-1).Result()
+		if err != nil || len(paths) == 0 {
+			return err
+		}
+		keys := make([]string, len(paths))
+		for i, path := range paths {
+			keys[i] = rs.prefix + "directory:" + path
+		}
+		values, err := rs.client.MGet(rs.ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+
+		var live, entries []string
+		var expired []any
+		for i, value := range values {
+			if data, ok := value.(string); ok {
+				live = append(live, paths[i])
+				entries = append(entries, data)
+			} else {
+				expired = append(expired, paths[i])
+			}
+		}
+		if len(expired) > 0 {
+			if err := rs.client.ZRem(rs.ctx, rs.indexKey(), expired...).Err(); err != nil {
+				return err
+			}
+		}
+		if len(live) > 0 {
+			if err := fn(live, entries); err != nil {
+				return err
+			}
+		}
+
+		if len(paths) < indexPage {
+			return nil
+		}
+		start += int64(len(live)) // Removed paths no longer take up places
+	}
+}
+
+// escapeMatch escapes the characters MATCH patterns treat specially, so a
+// prefix or path is matched literally
+func escapeMatch(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// GetDirectoryColor retrieves stored color for a directory, marking it as
+// used in the index
+func (rs *RedisStore) GetDirectoryColor(directoryPath string) (RGB16, bool) {
+	key := rs.prefix + "directory:" + directoryPath
+	pipe := rs.client.Pipeline()
+	get := pipe.Get(rs.ctx, key)
+	pipe.ZAddXX(rs.ctx, rs.indexKey(), redis.Z{Score: float64(time.Now().UnixMilli()), Member: directoryPath})
+	pipe.Exec(rs.ctx) // Errors are reported by each command
+
+	data, err := get.Result()
+	if err == redis.Nil {
+		return RGB16{}, false // Key doesn't exist
+	}
+	if err != nil {
+		log.Printf("Redis error getting directory color: %v", err)
+		return RGB16{}, false
+	}
+
+	var entry ColorEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		log.Printf("Error unmarshaling color entry: %v", err)
+		return RGB16{}, false
+	}
+
+	return entry.Color16(), true
+}
+
+// SetDirectoryColor stores color for a directory, recording where it
+// came from
+func (rs *RedisStore) SetDirectoryColor(directoryPath string, color RGB16, source string) error {
+	data, err := json.Marshal(newColorEntry(color, source))
+	if err != nil {
+		return fmt.Errorf("error marshaling color entry: %w", err)
+	}
+
+	key := rs.prefix + "directory:" + directoryPath
+	pipe := rs.client.TxPipeline()
+	// Generated colors expire to prevent infinite growth
+	pipe.Set(rs.ctx, key, data, directoryTTL(rs.config, source))
+	pipe.ZAdd(rs.ctx, rs.indexKey(), redis.Z{Score: float64(time.Now().UnixMilli()), Member: directoryPath})
+	_, err = pipe.Exec(rs.ctx)
+	if err != nil {
+		log.Printf("Redis error setting directory color: %v", err)
+	}
+
+	return err
+}
+
+// CountDirectoryColors counts the directory colors in the index with
+// ZCARD. Colors that expired since the last clear or migration are still
+// counted; reading every key to leave them out would cost a round trip
+// per page.
+func (rs *RedisStore) CountDirectoryColors() (int, error) {
+	n, err := rs.client.ZCard(rs.ctx, rs.indexKey()).Result()
+	return int(n), err
+}
+
+// GetRerollHistory retrieves the colors a directory had before each
+// reroll, oldest first
+func (rs *RedisStore) GetRerollHistory(directoryPath string) []RGB16 {
+	data, err := rs.client.Get(rs.ctx, rs.prefix+"reroll:"+directoryPath).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		log.Printf("Redis error getting reroll history: %v", err)
+		return nil
+	}
+
+	var history []RGB16
+	if err := json.Unmarshal([]byte(data), &history); err != nil {
+		log.Printf("Error unmarshaling reroll history: %v", err)
+		return nil
+	}
+	return history
+}
+
+// SetRerollHistory stores the reroll history of a directory. Like
+// rerolled colors, it never expires.
+func (rs *RedisStore) SetRerollHistory(directoryPath string, history []RGB16) error {
+	key := rs.prefix + "reroll:" + directoryPath
+	if len(history) == 0 {
+		return rs.client.Del(rs.ctx, key).Err()
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("error marshaling reroll history: %w", err)
+	}
+	return rs.client.Set(rs.ctx, key, data, 0).Err()
+}
+
+// GetLastClaudeColor retrieves the last used Claude theme color
+func (rs *RedisStore) GetLastClaudeColor() (RGB16, bool) {
+	key := rs.prefix + "claude:last"
+	data, err := rs.client.Get(rs.ctx, key).Result()
+	if err == redis.Nil {
+		return RGB16{}, false
+	}
+	if err != nil {
+		log.Printf("Redis error getting Claude color: %v", err)
+		return RGB16{}, false
+	}
+
+	var entry ColorEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		log.Printf("Error unmarshaling Claude color entry: %v", err)
+		return RGB16{}, false
+	}
+
+	// Only return if it's recent
+	if time.Since(entry.Timestamp) > rs.config.ClaudeTTL {
+		return RGB16{}, false
+	}
+
+	return entry.Color16(), true
+}
+
+// SetLastClaudeColor stores the last used Claude theme color
+func (rs *RedisStore) SetLastClaudeColor(color RGB16) error {
+	entry := newColorEntry(color, "claude")
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error marshaling Claude color entry: %w", err)
+	}
+
+	key := rs.prefix + "claude:last"
+	// Claude colors expire to allow theme variation
+	err = rs.client.Set(rs.ctx, key, data, rs.config.ClaudeTTL).Err()
+	if err != nil {
+		log.Printf("Redis error setting Claude color: %v", err)
+	}
+
+	return err
+}
+
+// GetCycleState retrieves the harmony walk state of `color cycle`
+func (rs *RedisStore) GetCycleState() (CycleState, bool) {
+	data, err := rs.client.Get(rs.ctx, rs.prefix+"cycle:state").Result()
+	if err == redis.Nil {
+		return CycleState{}, false
+	}
+	if err != nil {
+		log.Printf("Redis error getting cycle state: %v", err)
+		return CycleState{}, false
+	}
+
+	var state CycleState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		log.Printf("Error unmarshaling cycle state: %v", err)
+		return CycleState{}, false
+	}
+
+	return state, true
+}
+
+// SetCycleState stores the harmony walk state of `color cycle`
+func (rs *RedisStore) SetCycleState(state CycleState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error marshaling cycle state: %w", err)
+	}
+
+	// Forget the base color after a while without cycling
+	err = rs.client.Set(rs.ctx, rs.prefix+"cycle:state", data, rs.config.CycleTTL).Err()
+	if err != nil {
+		log.Printf("Redis error setting cycle state: %v", err)
+	}
+
+	return err
+}
+
+// eventsPage is how many events each XREVRANGE reads
+const eventsPage = 100
+
+// AddEvent records an applied color in a stream capped at roughly
+// persistence.history_limit events
+func (rs *RedisStore) AddEvent(event ColorEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshaling color event: %w", err)
+	}
+	return rs.client.XAdd(rs.ctx, &redis.XAddArgs{
+		Stream: rs.prefix + "events",
+		MaxLen: int64(rs.config.HistoryLimit),
+		Approx: true,
+		Values: []string{"event", string(data)},
+	}).Err()
+}
+
+// GetEvents retrieves the events matching filter, newest first, reading
+// the stream a page at a time and stopping at filter.Since
+func (rs *RedisStore) GetEvents(filter EventFilter) ([]ColorEvent, error) {
+	start := "-"
+	if !filter.Since.IsZero() {
+		start = strconv.FormatInt(filter.Since.UnixMilli(), 10)
+	}
+	return pageEvents(filter, func(end string) ([]redis.XMessage, error) {
+		return rs.client.XRevRangeN(rs.ctx, rs.prefix+"events", end, start, eventsPage).Result()
+	})
+}
+
+// pageEvents collects the events matching filter from pages of stream
+// messages, newest first. page reads up to eventsPage messages from end
+// backwards.
+func pageEvents(filter EventFilter, page func(end string) ([]redis.XMessage, error)) ([]ColorEvent, error) {
+	var events []ColorEvent
+	for end := "+"; !filter.Full(events); {
+		messages, err := page(end)
+		if err != nil {
+			return events, err
+		}
+		for _, message := range messages {
+			data, _ := message.Values["event"].(string)
+			var event ColorEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				log.Printf("Skipping unreadable event %s: %v", message.ID, err)
+				continue
+			}
+			if filter.Match(event) {
+				events = append(events, event)
+			}
+			if filter.Full(events) {
+				break
+			}
+		}
+		if len(messages) < eventsPage {
+			break
+		}
+		end = "(" + messages[len(messages)-1].ID
+	}
+	return events, nil
+}
+
+// ClearColorCache removes the colors this store wrote, a page of keys at
+// a time. Directory colors are found through the index rather than a scan
+// of the whole database. Saved themes are kept, and so are pins unless all
+// is set; other keys under the prefix are never touched.
+func (rs *RedisStore) ClearColorCache(all bool) error {
+	err := rs.directories(func(paths, _ []string) error {
+		keys := make([]string, len(paths))
+		for i, path := range paths {
+			keys[i] = rs.prefix + "directory:" + path
+		}
+		return rs.client.Unlink(rs.ctx, keys...).Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	// Rerolls and pins are few, so scanning for them is cheap
+	families := []string{"reroll:"}
+	if all {
+		families = append(families, "pin:")
+	}
+	for _, family := range families {
+		err := rs.scan(rs.prefix+family, func(keys []string) error {
+			return rs.client.Unlink(rs.ctx, keys...).Err()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	keys := []string{"claude:last", "cycle:state", "last", "events", "index:directories", canonicalMigrationKey}
+	for i, key := range keys {
+		keys[i] = rs.prefix + key
+	}
+	return rs.client.Unlink(rs.ctx, keys...).Err()
+}
+
+// GetLastColor retrieves the last color applied to the terminal
+func (rs *RedisStore) GetLastColor() (RGB16, bool) {
+	data, err := rs.client.Get(rs.ctx, rs.prefix+"last").Result()
+	if err == redis.Nil {
+		return RGB16{}, false
+	}
+	if err != nil {
+		log.Printf("Redis error getting last color: %v", err)
+		return RGB16{}, false
+	}
+
+	var entry ColorEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		log.Printf("Error unmarshaling last color entry: %v", err)
+		return RGB16{}, false
+	}
+
+	return entry.Color16(), true
+}
+
+// SetLastColor stores the last color applied to the terminal
+func (rs *RedisStore) SetLastColor(color RGB16) error {
+	data, err := json.Marshal(newColorEntry(color, "applied"))
+	if err != nil {
+		return fmt.Errorf("error marshaling last color entry: %w", err)
+	}
+
+	err = rs.client.Set(rs.ctx, rs.prefix+"last", data, rs.config.DirectoryTTL).Err()
+	if err != nil {
+		log.Printf("Redis error setting last color: %v", err)
+	}
+
+	return err
+}
+
+// GetTheme retrieves a saved theme
+func (rs *RedisStore) GetTheme(name string) (Theme, bool, error) {
+	data, err := rs.client.Get(rs.ctx, rs.prefix+"theme:"+name).Result()
+	if err == redis.Nil {
+		return Theme{}, false, nil
+	}
+	if err != nil {
+		return Theme{}, false, err
+	}
+
+	var theme Theme
+	if err := json.Unmarshal([]byte(data), &theme); err != nil {
+		return Theme{}, false, fmt.Errorf("error unmarshaling theme %s: %w", name, err)
+	}
+	return theme, true, nil
+}
+
+// SetTheme saves a theme. Themes never expire.
+func (rs *RedisStore) SetTheme(theme Theme) error {
+	data, err := json.Marshal(theme)
+	if err != nil {
+		return fmt.Errorf("error marshaling theme: %w", err)
+	}
+	return rs.client.Set(rs.ctx, rs.prefix+"theme:"+theme.Name, data, 0).Err()
+}
+
+// ListThemes retrieves every saved theme sorted by name
+func (rs *RedisStore) ListThemes() ([]Theme, error) {
+	seen := map[string]bool{}
+	var themes []Theme
+	err := rs.scan(rs.prefix+"theme:", func(keys []string) error {
+		values, err := rs.client.MGet(rs.ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok || seen[keys[i]] {
+				continue
+			}
+			seen[keys[i]] = true
+
+			var theme Theme
+			if err := json.Unmarshal([]byte(data), &theme); err != nil {
+				log.Printf("Skipping %s: %v", keys[i], err)
+				continue
+			}
+			themes = append(themes, theme)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(themes, func(i, j int) bool { return themes[i].Name < themes[j].Name })
+	return themes, nil
+}
+
+// DeleteTheme removes a saved theme, reporting whether it existed
+func (rs *RedisStore) DeleteTheme(name string) (bool, error) {
+	n, err := rs.client.Del(rs.ctx, rs.prefix+"theme:"+name).Result()
+	return n > 0, err
+}
+
+// SetPin pins a directory color. Pins never expire.
+func (rs *RedisStore) SetPin(directoryPath string, color RGB16, subtree bool) error {
+	entry := newColorEntry(color, "pinned")
+	entry.Subtree = subtree
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error marshaling pin: %w", err)
+	}
+	return rs.client.Set(rs.ctx, rs.prefix+"pin:"+directoryPath, data, 0).Err()
+}
+
+// GetPin retrieves the pin covering a directory: its own, or the nearest
+// subtree pin of an ancestor. The pinned path is returned with it.
+func (rs *RedisStore) GetPin(directoryPath string) (Pin, bool) {
+	var paths, keys []string
+	for dir := directoryPath; ; dir = path.Dir(dir) {
+		paths = append(paths, dir)
+		keys = append(keys, rs.prefix+"pin:"+dir)
+		if dir == path.Dir(dir) {
+			break
+		}
+	}
+
+	values, err := rs.client.MGet(rs.ctx, keys...).Result()
+	if err != nil {
+		log.Printf("Redis error getting pins: %v", err)
+		return Pin{}, false
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var entry ColorEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			continue
+		}
+		if i == 0 || entry.Subtree {
+			return Pin{Path: paths[i], Entry: entry}, true
+		}
+	}
+	return Pin{}, false
+}
+
+// DeletePin removes a directory's own pin, reporting whether it had one
+func (rs *RedisStore) DeletePin(directoryPath string) (bool, error) {
+	n, err := rs.client.Del(rs.ctx, rs.prefix+"pin:"+directoryPath).Result()
+	return n > 0, err
+}
+
+// ListPins retrieves every pinned directory sorted by path
+func (rs *RedisStore) ListPins() ([]Pin, error) {
+	seen := map[string]bool{}
+	var pins []Pin
+	err := rs.scan(rs.prefix+"pin:", func(keys []string) error {
+		values, err := rs.client.MGet(rs.ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+		for i, value := range values {
+			data, ok := value.(string)
+			if !ok || seen[keys[i]] {
+				continue // Removed since listing
+			}
+			seen[keys[i]] = true
+
+			var entry ColorEntry
+			if err := json.Unmarshal([]byte(data), &entry); err != nil {
+				continue
+			}
+			pins = append(pins, Pin{Path: strings.TrimPrefix(keys[i], rs.prefix+"pin:"), Entry: entry})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Path < pins[j].Path })
+	return pins, nil
+}
+
+// MigrateDirectoryKeys rewrites every color:directory:* key to the key
+// canonical returns for its path. When several keys share a canonical key
+// the most recent entry wins and keeps the longest remaining lifetime.
+func (rs *RedisStore) MigrateDirectoryKeys(canonical func(path string) (string, bool), force bool) (MigrationResult, error) {
+	var result MigrationResult
+	if !force {
+		done, err := rs.client.Exists(rs.ctx, rs.prefix+canonicalMigrationKey).Result()
+		if err != nil {
+			return result, err
+		}
+		if done > 0 {
+			result.AlreadyDone = true
+			return result, nil
+		}
+	}
+
+	groups := map[string][]storedEntry{}
+	seen := map[string]bool{}
+	err := rs.scan(rs.prefix+"directory:", func(keys []string) error {
+		values, err := rs.client.MGet(rs.ctx, keys...).Result()
+		if err != nil {
+			return err
+		}
+		pipe := rs.client.Pipeline()
+		ttls := make([]*redis.DurationCmd, len(keys))
+		for i, key := range keys {
+			ttls[i] = pipe.TTL(rs.ctx, key)
+		}
+		if _, err := pipe.Exec(rs.ctx); err != nil {
+			return err
+		}
+
+		for i, key := range keys {
+			data, ok := values[i].(string)
+			if !ok || seen[key] {
+				continue // Expired since listing
+			}
+			seen[key] = true
+
+			path := strings.TrimPrefix(key, rs.prefix+"directory:")
+			target, ok := canonical(path)
+			if !ok {
+				result.Skipped = append(result.Skipped, path)
+				continue
+			}
+			var entry ColorEntry
+			if err := json.Unmarshal([]byte(data), &entry); err != nil {
+				log.Printf("Skipping unreadable %s: %v", key, err)
+				continue
+			}
+
+			target = rs.prefix + "directory:" + target
+			groups[target] = append(groups[target], storedEntry{key, data, entry, ttls[i].Val()})
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for target, group := range groups {
+		winner, ttl := migrationWinner(group)
+		// Index colors stored before the index existed, keeping later use
+		member := strings.TrimPrefix(target, rs.prefix+"directory:")
+		index := redis.Z{Score: float64(winner.entry.Timestamp.UnixMilli()), Member: member}
+		if err := rs.client.ZAddNX(rs.ctx, rs.indexKey(), index).Err(); err != nil {
+			return result, err
+		}
+		if len(group) == 1 && group[0].key == target {
+			continue
+		}
+
+		if err := rs.client.Set(rs.ctx, target, winner.data, ttl).Err(); err != nil {
+			return result, err
+		}
+		for _, s := range group {
+			if s.key == target {
+				continue
+			}
+			pipe := rs.client.TxPipeline()
+			pipe.Del(rs.ctx, s.key)
+			pipe.ZRem(rs.ctx, rs.indexKey(), strings.TrimPrefix(s.key, rs.prefix+"directory:"))
+			if _, err := pipe.Exec(rs.ctx); err != nil {
+				return result, err
+			}
+			if s.key == winner.key {
+				result.Rewritten++
+			} else {
+				result.Merged++
+			}
+		}
+	}
+
+	// Reading the index through drops the colors that have expired
+	if err := rs.directories(func(_, _ []string) error { return nil }); err != nil {
+		return result, err
+	}
+	return result, rs.client.Set(rs.ctx, rs.prefix+canonicalMigrationKey, time.Now().Format(time.RFC3339), 0).Err()
+}
+
+// GetConnectionStatus returns Redis connection status
+func (rs *RedisStore) GetConnectionStatus() string {
+	_, err := rs.client.Ping(rs.ctx).Result()
+	if err != nil {
+		return fmt.Sprintf("⚠️ Redis connection issues at %s: %v", rs.client.Options().Addr, err)
+	}
+
+	return fmt.Sprintf("✅ Redis connected at %s (colors will persist)", rs.client.Options().Addr)
+}
+
+// Close closes the Redis connection
+func (rs *RedisStore) Close() error {
+	return rs.client.Close()
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestEscapeMatch(t *testing.T) {
+	tests := map[string]string{
+		"color:":           "color:",
+		"team*:":           `team\*:`,
+		"a?b[c]":           `a\?b\[c\]`,
+		`C:\Users\me:`:     `C:\\Users\\me:`,
+		"color:directory:": "color:directory:",
+	}
+	for s, want := range tests {
+		if got := escapeMatch(s); got != want {
+			t.Errorf("escapeMatch(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+// eventStream fakes XREVRANGE over n events with IDs 1-0 to n-0, every
+// third one from `color claude` and the rest from `color set`
+func eventStream(t *testing.T, n int) func(end string) ([]redis.XMessage, error) {
+	return func(end string) ([]redis.XMessage, error) {
+		from := n
+		if end != "+" {
+			id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(end, "("), "-0"))
+			if err != nil || !strings.HasPrefix(end, "(") {
+				t.Fatalf("page asked for end %q", end)
+			}
+			from = id - 1
+		}
+
+		var messages []redis.XMessage
+		for id := from; id >= 1 && len(messages) < eventsPage; id-- {
+			source := "set"
+			if id%3 == 0 {
+				source = "claude"
+			}
+			data, _ := json.Marshal(ColorEvent{Time: time.Unix(int64(id), 0), Source: source, New: RGB16{R: uint16(id)}})
+			messages = append(messages, redis.XMessage{ID: fmt.Sprintf("%d-0", id), Values: map[string]any{"event": string(data)}})
+		}
+		return messages, nil
+	}
+}
+
+func TestPageEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream int
+		filter EventFilter
+		want   int    // Events returned
+		first  uint16 // ID of the newest
+		last   uint16 // ID of the oldest
+	}{
+		{"within a page", 50, EventFilter{Limit: 20}, 20, 50, 31},
+		{"exactly a page", eventsPage, EventFilter{Limit: eventsPage}, eventsPage, eventsPage, 1},
+		{"across pages", 250, EventFilter{Limit: 150}, 150, 250, 101},
+		{"everything", 250, EventFilter{}, 250, 250, 1},
+		{"stream a page long", eventsPage, EventFilter{}, eventsPage, eventsPage, 1},
+		{"filtered across pages", 250, EventFilter{Source: "claude", Limit: 40}, 40, 249, 132},
+		{"more than there is", 30, EventFilter{Limit: 100}, 30, 30, 1},
+		{"empty stream", 0, EventFilter{}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := pageEvents(tt.filter, eventStream(t, tt.stream))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != tt.want {
+				t.Fatalf("got %d events, want %d", len(events), tt.want)
+			}
+			if tt.want == 0 {
+				return
+			}
+			if first, last := events[0].New.R, events[len(events)-1].New.R; first != tt.first || last != tt.last {
+				t.Errorf("events run from %d to %d, want %d to %d", first, last, tt.first, tt.last)
+			}
+			for i := 1; i < len(events); i++ {
+				if !events[i].Time.Before(events[i-1].Time) {
+					t.Fatalf("event %d isn't older than the one before", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rerollCandidates is how many random colors a reroll picks from
+const rerollCandidates = 48
+
+// rerollHistoryLimit is how many earlier colors a directory remembers
+const rerollHistoryLimit = 10
+
+// maxRerollSiblings caps how many sibling directories a reroll avoids
+const maxRerollSiblings = 64
+
+// RerollResult describes a rerolled directory color
+type RerollResult struct {
+	Key      string // The path the color is stored under
+	Previous RGB16  // The color before the reroll
+	Color    RGB16  // The new color
+	Siblings int    // Sibling colors the new color was kept away from
+	History  int    // Earlier colors left to go back to
+}
+
+// RerollDirectory replaces the stored color of a directory with a random
+// one that is perceptually far from its previous colors and from its
+// siblings. The previous color is kept for RerollBack.
+func (c *ColorManager) RerollDirectory(directoryPath string) (RerollResult, error) {
+	key, previous, err := c.rerollKey(directoryPath)
+	if err != nil {
+		return RerollResult{}, err
+	}
+
+	history := c.store.GetRerollHistory(key)
+	avoid := append([]RGB16{previous}, history...)
+	siblings := c.siblingColors(key)
+	avoid = append(avoid, siblings...)
+
+	// Keep the candidate whose nearest color to avoid is farthest away
+	generator := c.config.Directory
+	var best RGB16
+	bestDistance := -1.0
+	for i := 0; i < rerollCandidates; i++ {
+		candidate := c.HSVToRGB16(c.randFloat(), generator.Saturation.At(c.randFloat()), generator.Value.At(c.randFloat()))
+		nearest := math.Inf(1)
+		for _, color := range avoid {
+			nearest = math.Min(nearest, DistanceOKLab(candidate, color))
+		}
+		if nearest > bestDistance {
+			best, bestDistance = candidate, nearest
+		}
+	}
+
+	history = append(history, previous)
+	if len(history) > rerollHistoryLimit {
+		history = history[len(history)-rerollHistoryLimit:]
+	}
+	if err := c.store.SetDirectoryColor(key, best, "reroll"); err != nil {
+		return RerollResult{}, err
+	}
+	if err := c.store.SetRerollHistory(key, history); err != nil {
+		return RerollResult{}, err
+	}
+
+	return RerollResult{Key: key, Previous: previous, Color: best, Siblings: len(siblings), History: len(history)}, nil
+}
+
+// RerollBack returns a directory to the color it had before its last reroll
+func (c *ColorManager) RerollBack(directoryPath string) (RerollResult, error) {
+	key, previous, err := c.rerollKey(directoryPath)
+	if err != nil {
+		return RerollResult{}, err
+	}
+
+	history := c.store.GetRerollHistory(key)
+	if len(history) == 0 {
+		return RerollResult{}, fmt.Errorf("%s has no earlier colors", key)
+	}
+	color := history[len(history)-1]
+	history = history[:len(history)-1]
+
+	if err := c.store.SetDirectoryColor(key, color, "reroll"); err != nil {
+		return RerollResult{}, err
+	}
+	if err := c.store.SetRerollHistory(key, history); err != nil {
+		return RerollResult{}, err
+	}
+
+	return RerollResult{Key: key, Previous: previous, Color: color, History: len(history)}, nil
+}
+
+// rerollKey returns the key a directory's rerolled color is stored under
+// and its current color there. Directories whose color comes from a pin,
+// .colorrc or rule can't be rerolled, nor can those whose key is pinned.
+func (c *ColorManager) rerollKey(directoryPath string) (string, RGB16, error) {
+	directoryPath = CanonicalPath(directoryPath)
+
+	if pin, ok := c.PinFor(directoryPath); ok {
+		return "", RGB16{}, fmt.Errorf("%s is pinned on %s; unpin it first", directoryPath, pin.Path)
+	}
+	settings := c.DirectorySettings(directoryPath)
+	if !settings.IsEmpty() {
+		return "", RGB16{}, fmt.Errorf("%s is colored by %s", directoryPath, strings.Join(settings.Sources, ", "))
+	}
+
+	key, _, _ := c.colorKey(directoryPath)
+	if pin, ok := c.PinFor(key); ok {
+		return "", RGB16{}, fmt.Errorf("%s shares the color of %s, which is pinned; unpin it first", directoryPath, pin.Path)
+	}
+	color, found := c.store.GetDirectoryColor(key)
+	if !found {
+		color = c.hashDirectoryColor(key, settings)
+	}
+	return key, color, nil
+}
+
+// siblingColors returns the stored or generated colors of the directories
+// next to key
+func (c *ColorManager) siblingColors(key string) []RGB16 {
+	parent := filepath.Dir(key)
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return nil
+	}
+
+	var colors []RGB16
+	for _, entry := range entries {
+		if !entry.IsDir() || len(colors) == maxRerollSiblings {
+			continue
+		}
+		sibling := c.pathKey(filepath.Join(parent, entry.Name()))
+		if sibling == key {
+			continue
+		}
+		color, found := c.store.GetDirectoryColor(sibling)
+		if !found {
+			color = c.hashDirectoryColor(sibling, DirectorySettings{})
+		}
+		colors = append(colors, color)
+	}
+	return colors
+}
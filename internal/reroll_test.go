@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRerollRefusesPinnedProject(t *testing.T) {
+	root, src := gitProject(t)
+	c := NewColorManager(memoryConfig())
+	color := RGB{R: 0x01, G: 0x02, B: 0x03}.To16()
+	if _, err := c.PinDirectory(root, &color, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.RerollDirectory(src); err == nil {
+		t.Errorf("rerolling %s replaced the color of its pinned root", src)
+	}
+	if _, err := c.RerollBack(src); err == nil {
+		t.Errorf("rerolling %s back replaced the color of its pinned root", src)
+	}
+}
+
+func TestRerollKeepsRepositoryPin(t *testing.T) {
+	root, src := gitProject(t)
+	c := NewColorManager(memoryConfig())
+	color := RGB{R: 0x01, G: 0x02, B: 0x03}.To16()
+	if _, err := c.PinDirectory(root, &color, false); err != nil {
+		t.Fatal(err)
+	}
+
+	c.RerollDirectory(src) // Whether or not it's allowed, the pin stays
+	pin, ok := c.PinFor(root)
+	if !ok || pin.Entry.Color16() != color {
+		t.Fatalf("pin on %s is %v, %v after rerolling %s", root, pin, ok, src)
+	}
+}
+
+func TestRerolledColorsDontExpire(t *testing.T) {
+	root, _ := gitProject(t)
+	c := NewColorManager(memoryConfig())
+	store := c.store.(*MemoryStore)
+
+	first, err := c.RerollDirectory(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.RerollDirectory(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	later := time.Now().Add(2 * c.config.Persistence.DirectoryTTL)
+	store.now = func() time.Time { return later }
+	if got, ok := store.GetDirectoryColor(second.Key); !ok || got != second.Color {
+		t.Errorf("rerolled color came back as %v, %v after directory_ttl; want %v", got, ok, second.Color)
+	}
+	if history := store.GetRerollHistory(second.Key); len(history) != 2 || history[1] != first.Color {
+		t.Errorf("reroll history is %v after directory_ttl", history)
+	}
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// Rule maps a path pattern to fixed colors, palettes or generator options.
+// Rules are evaluated by descending priority, then file order; a matching
+// rule with Stop set ends the evaluation.
+type Rule struct {
+	Name     string
+	Pattern  string         // Glob such as "~/work/prod-*/**"
+	Regex    *regexp.Regexp // Alternative to Pattern
+	Priority int
+	Stop     bool
+	Settings DirectorySettings
+	Line     int // Line in the configuration file
+
+	canonical string // Pattern with symlinks in its literal directories resolved
+}
+
+// Matches reports whether the rule applies to an absolute path, or to a
+// hostname for host rules
+func (r Rule) Matches(path string) bool {
+	if r.Regex != nil {
+		return r.Regex.MatchString(path)
+	}
+	return MatchGlob(r.Pattern, path) || r.canonical != "" && MatchGlob(r.canonical, path)
+}
+
+// Describe returns a short human-readable identification of the rule
+func (r Rule) Describe() string {
+	name := r.Name
+	if name == "" {
+		name = fmt.Sprintf("line %d", r.Line)
+	}
+	if r.Regex != nil {
+		return fmt.Sprintf("%s (regex %s)", name, r.Regex)
+	}
+	return fmt.Sprintf("%s (%s)", name, r.Pattern)
+}
+
+// decodeRules decodes an array of rule tables such as [[rules]] or [[hosts]].
+// kind prefixes the rule description in the settings sources.
+func (d *configDecoder) decodeRules(root *tomlTable, section, kind string) []Rule {
+	v, ok := root.get(section)
+	if !ok {
+		return nil
+	}
+	tables, ok := v.Value.([]*tomlTable)
+	if !ok {
+		d.fail(v, "", "%s must be declared as [[%s]] tables", section, section)
+		return nil
+	}
+
+	var rules []Rule
+	for _, t := range tables {
+		// Rule keys are never overridden from the environment
+		sub := &configDecoder{file: d.file, noEnv: true}
+		rule := Rule{Line: t.Line}
+
+		sub.str(t, section, "name", &rule.Name)
+		sub.str(t, section, "pattern", &rule.Pattern)
+
+		var expr string
+		sub.str(t, section, "regex", &expr)
+		if expr != "" {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				sub.fail(t.Values["regex"], "", "%s.regex: %v", section, err)
+			}
+			rule.Regex = re
+		}
+		if (rule.Pattern == "") == (expr == "") {
+			sub.fail(tomlValue{Line: t.Line}, "", "rule must have exactly one of pattern or regex")
+		}
+
+		sub.integer(t, section, "priority", &rule.Priority, -1000000, 1000000)
+		sub.boolean(t, section, "stop", &rule.Stop)
+		rule.Settings = sub.directorySettings(t, section)
+		rule.Settings.Sources = []string{kind + " " + rule.Describe()}
+		sub.unknown(t, section)
+
+		d.errs = append(d.errs, sub.errs...)
+		rules = append(rules, rule)
+	}
+
+	// Stable sort keeps file order among equal priorities
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+	return rules
+}
+
+// MatchRules returns the rules applying to a path in evaluation order,
+// ending with the first matching rule that has stop set. A rule applies
+// when it matches the path as given or its canonical form.
+func (c *ColorManager) MatchRules(directoryPath string) []Rule {
+	path, err := filepath.Abs(directoryPath)
+	if err != nil {
+		return nil
+	}
+	return matchRules(c.config.Rules, path, CanonicalPath(path))
+}
+
+// matchRules returns the rules matching any of values in evaluation order
+func matchRules(rules []Rule, values ...string) []Rule {
+	var matched []Rule
+	for _, rule := range rules {
+		if !slices.ContainsFunc(values, rule.Matches) {
+			continue
+		}
+		matched = append(matched, rule)
+		if rule.Stop {
+			break
+		}
+	}
+	return matched
+}
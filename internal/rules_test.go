@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// decodeTestConfig decodes a configuration file's contents, ignoring the
+// environment
+func decodeTestConfig(t *testing.T, src string) *Config {
+	t.Helper()
+	root, err := parseTOML("config.toml", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := memoryConfig()
+	d := &configDecoder{file: "config.toml", noEnv: true}
+	d.decode(root, cfg)
+	if len(d.errs) > 0 {
+		t.Fatal(d.errs)
+	}
+	return cfg
+}
+
+func ruleNames(rules []Rule) []string {
+	names := []string{}
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+func TestMatchRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		path  string
+		want  []string
+	}{
+		{
+			name: "higher priority first",
+			rules: `
+[[rules]]
+name = "low"
+pattern = "/work/**"
+[[rules]]
+name = "high"
+pattern = "/work/**"
+priority = 10
+[[rules]]
+name = "middle"
+pattern = "/work/**"
+priority = 5
+[[rules]]
+name = "negative"
+pattern = "/work/**"
+priority = -1`,
+			path: "/work/api",
+			want: []string{"high", "middle", "low", "negative"},
+		},
+		{
+			name: "file order among equal priorities",
+			rules: `
+[[rules]]
+name = "a"
+pattern = "/work/**"
+priority = 1
+[[rules]]
+name = "b"
+pattern = "/work/**"
+[[rules]]
+name = "c"
+pattern = "/work/**"
+priority = 1
+[[rules]]
+name = "d"
+pattern = "/work/**"`,
+			path: "/work/api",
+			want: []string{"a", "c", "b", "d"},
+		},
+		{
+			name: "stop cuts off lower rules",
+			rules: `
+[[rules]]
+name = "low"
+pattern = "/work/**"
+[[rules]]
+name = "stopper"
+pattern = "/work/prod-*"
+priority = 5
+stop = true
+[[rules]]
+name = "high"
+pattern = "/work/**"
+priority = 10`,
+			path: "/work/prod-db",
+			want: []string{"high", "stopper"},
+		},
+		{
+			name: "stop only applies when its rule matches",
+			rules: `
+[[rules]]
+name = "low"
+pattern = "/work/**"
+[[rules]]
+name = "stopper"
+pattern = "/work/prod-*"
+priority = 5
+stop = true`,
+			path: "/work/staging",
+			want: []string{"low"},
+		},
+		{
+			name: "regex rules",
+			rules: `
+[[rules]]
+name = "glob"
+pattern = "/srv/**"
+[[rules]]
+name = "regex"
+regex = "^/srv/[a-z]+-\\d+$"
+priority = 1`,
+			path: "/srv/web-2",
+			want: []string{"regex", "glob"},
+		},
+		{
+			name: "no match",
+			rules: `
+[[rules]]
+name = "other"
+pattern = "/elsewhere/**"`,
+			path: "/work/api",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := decodeTestConfig(t, tt.rules)
+			if got := ruleNames(matchRules(cfg.Rules, tt.path)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesOverrideColorrc(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir()) // Empty trust store
+	dir := t.TempDir()
+	project := filepath.Join(dir, "project")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	colorrc := "color = \"#112233\"\nsaturation = [0.1, 0.2]\n"
+	if err := os.WriteFile(filepath.Join(project, ColorrcName), []byte(colorrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := decodeTestConfig(t, `
+[colorrc]
+allow = ["`+dir+`/**"]
+
+[[rules]]
+name = "low"
+pattern = "`+dir+`/**"
+color = "#445566"
+value = [0.3, 0.4]
+
+[[rules]]
+name = "high"
+pattern = "`+dir+`/**"
+priority = 10
+color = "#778899"`)
+	c := NewColorManager(cfg)
+
+	settings := c.DirectorySettings(project)
+	if want := (RGB{R: 0x77, G: 0x88, B: 0x99}); settings.Color == nil || *settings.Color != want {
+		t.Errorf("color %v, want the high priority rule's %v", settings.Color, want)
+	}
+	if want := (Range{0.1, 0.2}); settings.Saturation == nil || *settings.Saturation != want {
+		t.Errorf("saturation %v, want the .colorrc's %v", settings.Saturation, want)
+	}
+	if want := (Range{0.3, 0.4}); settings.Value == nil || *settings.Value != want {
+		t.Errorf("value %v, want the low priority rule's %v", settings.Value, want)
+	}
+
+	want := []string{
+		filepath.Join(project, ColorrcName),
+		"rule low (" + dir + "/**)",
+		"rule high (" + dir + "/**)",
+	}
+	if !reflect.DeepEqual(settings.Sources, want) {
+		t.Errorf("sources %q, want %q", settings.Sources, want)
+	}
+}
+
+func TestRulesMatchSymlinkedPaths(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir()) // Empty trust store
+	dir := CanonicalPath(t.TempDir())
+	real, link := filepath.Join(dir, "real"), filepath.Join(dir, "link")
+	if err := os.MkdirAll(filepath.Join(real, "proj"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := decodeTestConfig(t, `
+[[rules]]
+name = "linked"
+pattern = "`+link+`/**"
+color = "#5a1e1e"
+
+[[rules]]
+name = "real"
+pattern = "`+real+`/proj"
+value = [0.3, 0.4]`)
+	c := NewColorManager(cfg)
+
+	for _, path := range []string{filepath.Join(link, "proj"), filepath.Join(real, "proj")} {
+		if got, want := ruleNames(c.MatchRules(path)), []string{"linked", "real"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: matched %v, want %v", path, got, want)
+		}
+	}
+
+	settings := c.DirectorySettings(CanonicalPath(filepath.Join(link, "proj")))
+	if want := (RGB{R: 0x5a, G: 0x1e, B: 0x1e}); settings.Color == nil || *settings.Color != want {
+		t.Errorf("color %v, want the symlinked rule's %v", settings.Color, want)
+	}
+}
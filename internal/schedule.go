@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScheduleWindow transforms generated colors during a time of day. Windows
+// whose end is before their start wrap around midnight.
+type ScheduleWindow struct {
+	Name      string
+	Start     int     // Minutes after midnight
+	End       int     // Minutes after midnight
+	Lightness float64 // OKLab lightness offset, negative to dim
+	Warmth    float64 // 0 keeps the white point, 1 shifts it to warm light
+	Chroma    float64 // Chroma scale, below 1 to mute colors
+	Line      int     // Line in the configuration file
+}
+
+// Contains reports whether the window covers a time of day
+func (w ScheduleWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start <= w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// Describe returns the window name and its times
+func (w ScheduleWindow) Describe() string {
+	name := w.Name
+	if name == "" {
+		name = fmt.Sprintf("window on line %d", w.Line)
+	}
+	return fmt.Sprintf("%s (%s–%s)", name, formatClock(w.Start), formatClock(w.End))
+}
+
+// formatClock formats minutes after midnight as HH:MM
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Transform applies the window's lightness, warmth and chroma to a color
+func (w ScheduleWindow) Transform(color RGB16) RGB16 {
+	if w.Warmth != 0 {
+		// Scale linear light toward an incandescent white point
+		r := srgbToLinear(float64(color.R) / 65535.0)
+		g := srgbToLinear(float64(color.G)/65535.0) * (1 - 0.12*w.Warmth)
+		b := srgbToLinear(float64(color.B)/65535.0) * (1 - 0.35*w.Warmth)
+		color = RGB16{
+			R: toChannel16(linearToSRGB(r)),
+			G: toChannel16(linearToSRGB(g)),
+			B: toChannel16(linearToSRGB(b)),
+		}
+	}
+
+	if w.Lightness == 0 && w.Chroma == 1 {
+		return color
+	}
+	lch := RGB16ToOKLab(color).ToOKLCH()
+	lch.L += w.Lightness
+	lch.C *= w.Chroma
+	return OKLabToRGB16(lch.ToOKLab())
+}
+
+// ScheduleAt returns the first schedule window covering t
+func (c *ColorManager) ScheduleAt(t time.Time) (ScheduleWindow, bool) {
+	for _, window := range c.config.Schedule {
+		if window.Contains(t) {
+			return window, true
+		}
+	}
+	return ScheduleWindow{}, false
+}
+
+// applyScheduleAt transforms a color by the window active at t
+func (c *ColorManager) applyScheduleAt(color RGB16, t time.Time) RGB16 {
+	window, ok := c.ScheduleAt(t)
+	if !ok {
+		return color
+	}
+	return window.Transform(color)
+}
+
+// decodeSchedule decodes the [[schedule]] array of tables
+func (d *configDecoder) decodeSchedule(root *tomlTable) []ScheduleWindow {
+	v, ok := root.get("schedule")
+	if !ok {
+		return nil
+	}
+	tables, ok := v.Value.([]*tomlTable)
+	if !ok {
+		d.fail(v, "", "schedule must be declared as [[schedule]] tables")
+		return nil
+	}
+
+	var windows []ScheduleWindow
+	for _, t := range tables {
+		// Schedule keys are never overridden from the environment
+		sub := &configDecoder{file: d.file, noEnv: true}
+		window := ScheduleWindow{Chroma: 1, Line: t.Line}
+
+		sub.str(t, "schedule", "name", &window.Name)
+		clocks := 0
+		for _, key := range []string{"start", "end"} {
+			v, ok := t.Values[key]
+			if !ok {
+				sub.fail(tomlValue{Line: t.Line}, "", "schedule window must set %s", key)
+				continue
+			}
+			var clock string
+			sub.str(t, "schedule", key, &clock)
+			minutes, err := parseClock(clock)
+			if err != nil {
+				sub.fail(v, "", "schedule.%s: %v", key, err)
+				continue
+			}
+			clocks++
+			if key == "start" {
+				window.Start = minutes
+			} else {
+				window.End = minutes
+			}
+		}
+		if clocks == 2 && window.Start == window.End {
+			sub.fail(t.Values["end"], "", "schedule window starts and ends at %s, so it never applies", formatClock(window.Start))
+		}
+
+		sub.float(t, "schedule", "lightness", &window.Lightness, -0.5, 0.5)
+		sub.float(t, "schedule", "warmth", &window.Warmth, 0, 1)
+		sub.float(t, "schedule", "chroma", &window.Chroma, 0, 2)
+		sub.unknown(t, "schedule")
+
+		d.errs = append(d.errs, sub.errs...)
+		windows = append(windows, window)
+	}
+	return windows
+}
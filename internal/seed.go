@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"math/rand"
+	"time"
+)
+
+// newSeed returns a clock-derived seed short enough to type back in
+func newSeed() int64 {
+	return time.Now().UnixNano() % 1000000000
+}
+
+// SetSeed makes all random generators deterministic. An explicit seed also
+// bypasses the remembered Claude color so the seeded theme is reproduced.
+func (c *ColorManager) SetSeed(seed int64) {
+	c.seed = seed
+	c.seeded = true
+	c.rng = rand.New(rand.NewSource(seed))
+}
+
+// Seed returns the seed of the random generator
+func (c *ColorManager) Seed() int64 {
+	return c.seed
+}
+
+// RandomnessUsed reports whether any generator has drawn from the seeded
+// random source, i.e. whether the seed is needed to reproduce the output
+func (c *ColorManager) RandomnessUsed() bool {
+	return c.randomUsed
+}
+
+// randFloat returns a random value in [0, 1)
+func (c *ColorManager) randFloat() float64 {
+	c.randomUsed = true
+	return c.rng.Float64()
+}
+
+// randIntn returns a random value in [0, n)
+func (c *ColorManager) randIntn(n int) int {
+	c.randomUsed = true
+	return c.rng.Intn(n)
+}
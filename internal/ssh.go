@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// RemoteHost returns the local hostname when the shell runs in an SSH session
+func RemoteHost() (string, bool) {
+	if !remoteSession() {
+		return "", false
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "", false
+	}
+	return host, true
+}
+
+// SSHHostName extracts the hostname from an ssh destination such as
+// "user@host" or "ssh://user@host:2222"
+func SSHHostName(destination string) string {
+	host := strings.TrimPrefix(destination, "ssh://")
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if strings.HasPrefix(destination, "ssh://") {
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+	}
+	return host
+}
+
+// HostSettings merges the host rules matching a hostname, higher priorities
+// overriding lower ones
+func (c *ColorManager) HostSettings(host string) DirectorySettings {
+	var settings DirectorySettings
+	matched := matchRules(c.config.Hosts, host)
+	for i := len(matched) - 1; i >= 0; i-- {
+		settings.merge(matched[i].Settings)
+	}
+	return settings
+}
+
+// HostColor returns the color of a host in the active appearance. Hosts
+// without a fixed color from a host rule are colored by hostname hash.
+func (c *ColorManager) HostColor(host string) RGB16 {
+	settings := c.HostSettings(host)
+	if settings.Color != nil {
+		if c.Appearance() == AppearanceLight && settings.LightColor != nil {
+			return settings.LightColor.To16()
+		}
+		return c.toAppearance16(settings.Color.To16())
+	}
+	return c.toAppearance16(c.hashDirectoryColor(host, settings))
+}
+
+// GenerateHostTheme returns the color of a host for `color ssh`
+func (c *ColorManager) GenerateHostTheme(host string) RGB {
+	return c.applyScheduleAt(c.HostColor(host), time.Now()).To8()
+}
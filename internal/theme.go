@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ansiNames are the iTerm2 AppleScript names of the 16 ANSI colors
+var ansiNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"bright black", "bright red", "bright green", "bright yellow",
+	"bright blue", "bright magenta", "bright cyan", "bright white",
+}
+
+// Theme is a named set of terminal colors
+type Theme struct {
+	Name       string    `json:"name"`
+	Background RGB16     `json:"background"`
+	Foreground RGB16     `json:"foreground"`
+	Palette    []RGB16   `json:"palette,omitempty"` // ANSI colors 0-15
+	Created    time.Time `json:"created"`
+
+	// Optional colors carried by imported schemes
+	Cursor        *RGB16 `json:"cursor,omitempty"`
+	CursorText    *RGB16 `json:"cursor_text,omitempty"`
+	Selection     *RGB16 `json:"selection,omitempty"`
+	SelectionText *RGB16 `json:"selection_text,omitempty"`
+}
+
+var themeNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateThemeName checks that a theme name is usable as a store key
+func ValidateThemeName(name string) error {
+	if !themeNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid theme name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// ReadITermTheme reads the background, foreground and ANSI palette of the
+// current iTerm2 session
+func (c *ColorManager) ReadITermTheme() (Theme, error) {
+	if remoteSession() {
+		return Theme{}, errors.New("terminal colors can't be read in an SSH session")
+	}
+
+	props := []string{"background color", "foreground color"}
+	for _, name := range ansiNames {
+		props = append(props, "ANSI "+name+" color")
+	}
+
+	script := fmt.Sprintf(`
+	tell application "iTerm2"
+		tell current session of current tab of current window
+			get {%s}
+		end tell
+	end tell
+	`, strings.Join(props, ", "))
+
+	output, err := exec.Command("osascript", "-e", script).Output()
+	if err != nil {
+		return Theme{}, fmt.Errorf("failed to read terminal colors: %w", err)
+	}
+
+	// Output is every channel in order: "0, 0, 0, 65535, ..."
+	parts := strings.Split(strings.TrimSpace(string(output)), ",")
+	if len(parts) != 3*len(props) {
+		return Theme{}, fmt.Errorf("unexpected terminal colors %q", strings.TrimSpace(string(output)))
+	}
+
+	colors := make([]RGB16, len(props))
+	for i, part := range parts {
+		val, err := strconv.ParseUint(strings.TrimSpace(part), 10, 16)
+		if err != nil {
+			return Theme{}, fmt.Errorf("unexpected terminal colors %q", strings.TrimSpace(string(output)))
+		}
+		channel := []*uint16{&colors[i/3].R, &colors[i/3].G, &colors[i/3].B}[i%3]
+		*channel = uint16(val)
+	}
+
+	return Theme{
+		Background: colors[0],
+		Foreground: colors[1],
+		Palette:    colors[2:],
+	}, nil
+}
+
+// LastTheme builds a theme from the last color applied by this tool, with
+// the foreground chosen for it
+func (c *ColorManager) LastTheme() (Theme, bool) {
+	color, ok := c.store.GetLastColor()
+	if !ok {
+		return Theme{}, false
+	}
+	return Theme{
+		Background: color,
+		Foreground: c.ForegroundFor(color.To8()).To16(),
+	}, true
+}
+
+// ApplyTheme sets the background, foreground and palette of a theme
+func (c *ColorManager) ApplyTheme(theme Theme) error {
+	if err := c.ApplyColor16(theme.Background, ColorChange{Source: "theme", Mode: theme.Name}); err != nil {
+		return err
+	}
+	return c.SetThemeColors(theme)
+}
+
+// SetThemeColors sets the foreground and palette of a theme, leaving the
+// background alone
+func (c *ColorManager) SetThemeColors(theme Theme) error {
+	if remoteSession() {
+		return setThemeColorsOSC(theme)
+	}
+
+	settings := []string{fmt.Sprintf("set foreground color to %s", iTermColor(theme.Foreground))}
+	for i, color := range theme.Palette {
+		if i < len(ansiNames) {
+			settings = append(settings, fmt.Sprintf("set ANSI %s color to %s", ansiNames[i], iTermColor(color)))
+		}
+	}
+	optional := []struct {
+		property string
+		color    *RGB16
+	}{
+		{"cursor color", theme.Cursor},
+		{"cursor text color", theme.CursorText},
+		{"selection color", theme.Selection},
+		{"selected text color", theme.SelectionText},
+	}
+	for _, o := range optional {
+		if o.color != nil {
+			settings = append(settings, fmt.Sprintf("set %s to %s", o.property, iTermColor(*o.color)))
+		}
+	}
+
+	script := fmt.Sprintf(`
+	tell application "iTerm2"
+		tell current session of current tab of current window
+			%s
+		end tell
+	end tell
+	`, strings.Join(settings, "\n\t\t\t"))
+
+	return exec.Command("osascript", "-e", script).Run()
+}
+
+// setThemeColorsOSC sends the foreground, palette, cursor and selection
+// colors of a theme as escape sequences. Terminals have no sequence for
+// the cursor text color, so it is left alone.
+func setThemeColorsOSC(theme Theme) error {
+	sequences := []string{oscColor("10", theme.Foreground)}
+	for i, color := range theme.Palette {
+		if i < len(ansiNames) {
+			sequences = append(sequences, oscColor(fmt.Sprintf("4;%d", i), color))
+		}
+	}
+	optional := []struct {
+		code  string
+		color *RGB16
+	}{
+		{"12", theme.Cursor},
+		{"17", theme.Selection},
+		{"19", theme.SelectionText},
+	}
+	for _, o := range optional {
+		if o.color != nil {
+			sequences = append(sequences, oscColor(o.code, *o.color))
+		}
+	}
+	return writeOSC(sequences...)
+}
+
+// iTermColor formats a color as an AppleScript RGB list
+func iTermColor(color RGB16) string {
+	return fmt.Sprintf("{%d, %d, %d}", color.R, color.G, color.B)
+}
+
+// SaveTheme stores a theme under its name
+func (c *ColorManager) SaveTheme(theme Theme) error {
+	if err := ValidateThemeName(theme.Name); err != nil {
+		return err
+	}
+	if theme.Created.IsZero() {
+		theme.Created = time.Now()
+	}
+	return c.store.SetTheme(theme)
+}
+
+// LoadTheme returns a stored theme
+func (c *ColorManager) LoadTheme(name string) (Theme, error) {
+	theme, ok, err := c.store.GetTheme(name)
+	if err != nil {
+		return Theme{}, err
+	}
+	if !ok {
+		return Theme{}, fmt.Errorf("no theme named %q", name)
+	}
+	return theme, nil
+}
+
+// ListThemes returns every stored theme sorted by name
+func (c *ColorManager) ListThemes() ([]Theme, error) {
+	return c.store.ListThemes()
+}
+
+// DeleteTheme removes a stored theme, reporting whether it existed
+func (c *ColorManager) DeleteTheme(name string) (bool, error) {
+	return c.store.DeleteTheme(name)
+}
+
+// DirectoryTheme returns the named theme a .colorrc or rule assigns to a
+// directory. Pinned directories have no theme.
+func (c *ColorManager) DirectoryTheme(directoryPath string) (Theme, bool) {
+	if _, pinned := c.PinFor(directoryPath); pinned {
+		return Theme{}, false
+	}
+	settings := c.DirectorySettings(CanonicalPath(directoryPath))
+	if settings.Theme == nil {
+		return Theme{}, false
+	}
+	theme, err := c.LoadTheme(*settings.Theme)
+	if err != nil {
+		return Theme{}, false
+	}
+	return theme, true
+}
@@ -0,0 +1,350 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExportFormats lists the formats themes can be exported to
+var ExportFormats = []string{
+	"itermcolors", "kitty", "alacritty", "wezterm", "foot", "xresources", "windows-terminal", "base16",
+}
+
+// DefaultPalette is the xterm ANSI palette, used for directory colors that
+// don't come with a palette of their own
+var DefaultPalette = []RGB16{
+	RGB{0x00, 0x00, 0x00}.To16(), RGB{0xcd, 0x00, 0x00}.To16(), RGB{0x00, 0xcd, 0x00}.To16(), RGB{0xcd, 0xcd, 0x00}.To16(),
+	RGB{0x00, 0x00, 0xee}.To16(), RGB{0xcd, 0x00, 0xcd}.To16(), RGB{0x00, 0xcd, 0xcd}.To16(), RGB{0xe5, 0xe5, 0xe5}.To16(),
+	RGB{0x7f, 0x7f, 0x7f}.To16(), RGB{0xff, 0x00, 0x00}.To16(), RGB{0x00, 0xff, 0x00}.To16(), RGB{0xff, 0xff, 0x00}.To16(),
+	RGB{0x5c, 0x5c, 0xff}.To16(), RGB{0xff, 0x00, 0xff}.To16(), RGB{0x00, 0xff, 0xff}.To16(), RGB{0xff, 0xff, 0xff}.To16(),
+}
+
+// DirectoryThemeFor returns the theme a directory is shown with: the named
+// theme its .colorrc or rule references, or else its generated color with a
+// matching foreground and the default palette
+func (c *ColorManager) DirectoryThemeFor(directoryPath string) Theme {
+	if theme, ok := c.DirectoryTheme(directoryPath); ok {
+		return theme
+	}
+	background := c.GenerateDirectoryTheme(directoryPath)
+	return Theme{
+		Background: background.To16(),
+		Foreground: c.ForegroundFor(background).To16(),
+		Palette:    DefaultPalette,
+	}
+}
+
+// ExportTheme writes a theme in another terminal's format. Themes saved
+// without a palette are exported with the default one.
+func ExportTheme(theme Theme, format string) ([]byte, error) {
+	if len(theme.Palette) == 0 {
+		theme.Palette = DefaultPalette
+	}
+	if len(theme.Palette) != 16 {
+		return nil, fmt.Errorf("theme %s has %d palette colors, exporting needs 16", theme.Name, len(theme.Palette))
+	}
+
+	var out string
+	switch format {
+	case "itermcolors":
+		out = exportITermColors(theme)
+	case "kitty":
+		out = exportKitty(theme)
+	case "alacritty":
+		out = exportAlacritty(theme)
+	case "wezterm":
+		out = exportWezTerm(theme)
+	case "foot":
+		out = exportFoot(theme)
+	case "xresources":
+		out = exportXresources(theme)
+	case "windows-terminal":
+		return exportWindowsTerminal(theme)
+	case "base16":
+		out = exportBase16(theme)
+	default:
+		return nil, fmt.Errorf("unknown export format %q (expected %s)", format, strings.Join(ExportFormats, ", "))
+	}
+	return []byte(out), nil
+}
+
+// hex8 formats a color as 8-bit "#rrggbb"
+func hex8(color RGB16) string {
+	return color.To8().Hex()
+}
+
+// orDefault returns the optional color, or fallback when it is unset
+func orDefault(color *RGB16, fallback RGB16) RGB16 {
+	if color != nil {
+		return *color
+	}
+	return fallback
+}
+
+// exportITermColors writes an iTerm2 property list at full precision
+func exportITermColors(theme Theme) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	entry := func(key string, color RGB16) {
+		component := func(v uint16) string {
+			return strconv.FormatFloat(float64(v)/65535, 'g', -1, 64)
+		}
+		fmt.Fprintf(&b, "\t<key>%s</key>\n\t<dict>\n", key)
+		fmt.Fprintf(&b, "\t\t<key>Alpha Component</key>\n\t\t<real>1</real>\n")
+		fmt.Fprintf(&b, "\t\t<key>Blue Component</key>\n\t\t<real>%s</real>\n", component(color.B))
+		fmt.Fprintf(&b, "\t\t<key>Color Space</key>\n\t\t<string>sRGB</string>\n")
+		fmt.Fprintf(&b, "\t\t<key>Green Component</key>\n\t\t<real>%s</real>\n", component(color.G))
+		fmt.Fprintf(&b, "\t\t<key>Red Component</key>\n\t\t<real>%s</real>\n", component(color.R))
+		b.WriteString("\t</dict>\n")
+	}
+
+	for i, color := range theme.Palette {
+		entry(fmt.Sprintf("Ansi %d Color", i), color)
+	}
+	entry("Background Color", theme.Background)
+	if theme.Cursor != nil {
+		entry("Cursor Color", *theme.Cursor)
+	}
+	if theme.CursorText != nil {
+		entry("Cursor Text Color", *theme.CursorText)
+	}
+	entry("Foreground Color", theme.Foreground)
+	if theme.SelectionText != nil {
+		entry("Selected Text Color", *theme.SelectionText)
+	}
+	if theme.Selection != nil {
+		entry("Selection Color", *theme.Selection)
+	}
+
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+// exportKitty writes a kitty.conf color include
+func exportKitty(theme Theme) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", theme.Name)
+	fmt.Fprintf(&b, "foreground %s\n", hex8(theme.Foreground))
+	fmt.Fprintf(&b, "background %s\n", hex8(theme.Background))
+	if theme.Cursor != nil {
+		fmt.Fprintf(&b, "cursor %s\n", hex8(*theme.Cursor))
+	}
+	if theme.CursorText != nil {
+		fmt.Fprintf(&b, "cursor_text_color %s\n", hex8(*theme.CursorText))
+	}
+	if theme.Selection != nil {
+		fmt.Fprintf(&b, "selection_background %s\n", hex8(*theme.Selection))
+	}
+	if theme.SelectionText != nil {
+		fmt.Fprintf(&b, "selection_foreground %s\n", hex8(*theme.SelectionText))
+	}
+	for i, color := range theme.Palette {
+		fmt.Fprintf(&b, "color%d %s\n", i, hex8(color))
+	}
+	return b.String()
+}
+
+// exportAlacritty writes Alacritty [colors] tables
+func exportAlacritty(theme Theme) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n[colors.primary]\n", theme.Name)
+	fmt.Fprintf(&b, "background = '%s'\nforeground = '%s'\n", hex8(theme.Background), hex8(theme.Foreground))
+	if theme.Cursor != nil || theme.CursorText != nil {
+		b.WriteString("\n[colors.cursor]\n")
+		if theme.CursorText != nil {
+			fmt.Fprintf(&b, "text = '%s'\n", hex8(*theme.CursorText))
+		}
+		if theme.Cursor != nil {
+			fmt.Fprintf(&b, "cursor = '%s'\n", hex8(*theme.Cursor))
+		}
+	}
+	if theme.Selection != nil || theme.SelectionText != nil {
+		b.WriteString("\n[colors.selection]\n")
+		if theme.SelectionText != nil {
+			fmt.Fprintf(&b, "text = '%s'\n", hex8(*theme.SelectionText))
+		}
+		if theme.Selection != nil {
+			fmt.Fprintf(&b, "background = '%s'\n", hex8(*theme.Selection))
+		}
+	}
+	for i, section := range []string{"normal", "bright"} {
+		fmt.Fprintf(&b, "\n[colors.%s]\n", section)
+		for j, key := range ansiKeys {
+			fmt.Fprintf(&b, "%s = '%s'\n", key, hex8(theme.Palette[i*8+j]))
+		}
+	}
+	return b.String()
+}
+
+// exportWezTerm writes a Lua module returning a WezTerm colors table, used
+// as config.colors = require 'name'
+func exportWezTerm(theme Theme) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- %s\nreturn {\n", theme.Name)
+	fmt.Fprintf(&b, "  foreground = '%s',\n  background = '%s',\n", hex8(theme.Foreground), hex8(theme.Background))
+	optional := []struct {
+		key   string
+		color *RGB16
+	}{
+		{"cursor_bg", theme.Cursor},
+		{"cursor_border", theme.Cursor},
+		{"cursor_fg", theme.CursorText},
+		{"selection_bg", theme.Selection},
+		{"selection_fg", theme.SelectionText},
+	}
+	for _, o := range optional {
+		if o.color != nil {
+			fmt.Fprintf(&b, "  %s = '%s',\n", o.key, hex8(*o.color))
+		}
+	}
+	for i, key := range []string{"ansi", "brights"} {
+		colors := make([]string, 8)
+		for j := range colors {
+			colors[j] = fmt.Sprintf("'%s'", hex8(theme.Palette[i*8+j]))
+		}
+		fmt.Fprintf(&b, "  %s = { %s },\n", key, strings.Join(colors, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// exportFoot writes foot.ini [colors] and [cursor] sections
+func exportFoot(theme Theme) string {
+	plain := func(color RGB16) string {
+		return strings.TrimPrefix(hex8(color), "#")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", theme.Name)
+	if theme.Cursor != nil {
+		// foot takes the cursor's text color first, then the cursor color
+		text := orDefault(theme.CursorText, theme.Background)
+		fmt.Fprintf(&b, "[cursor]\ncolor=%s %s\n\n", plain(text), plain(*theme.Cursor))
+	}
+	b.WriteString("[colors]\n")
+	fmt.Fprintf(&b, "foreground=%s\nbackground=%s\n", plain(theme.Foreground), plain(theme.Background))
+	for i, color := range theme.Palette[:8] {
+		fmt.Fprintf(&b, "regular%d=%s\n", i, plain(color))
+	}
+	for i, color := range theme.Palette[8:] {
+		fmt.Fprintf(&b, "bright%d=%s\n", i, plain(color))
+	}
+	if theme.SelectionText != nil {
+		fmt.Fprintf(&b, "selection-foreground=%s\n", plain(*theme.SelectionText))
+	}
+	if theme.Selection != nil {
+		fmt.Fprintf(&b, "selection-background=%s\n", plain(*theme.Selection))
+	}
+	return b.String()
+}
+
+// exportXresources writes X resources for any client
+func exportXresources(theme Theme) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "! %s\n", theme.Name)
+	fmt.Fprintf(&b, "*.foreground: %s\n*.background: %s\n", hex8(theme.Foreground), hex8(theme.Background))
+	if theme.Cursor != nil {
+		fmt.Fprintf(&b, "*.cursorColor: %s\n", hex8(*theme.Cursor))
+	}
+	for i, color := range theme.Palette {
+		fmt.Fprintf(&b, "*.color%d: %s\n", i, hex8(color))
+	}
+	return b.String()
+}
+
+// windowsTerminalScheme is a Windows Terminal color scheme in key order
+type windowsTerminalScheme struct {
+	Name                string `json:"name"`
+	Background          string `json:"background"`
+	Foreground          string `json:"foreground"`
+	CursorColor         string `json:"cursorColor,omitempty"`
+	SelectionBackground string `json:"selectionBackground,omitempty"`
+	Black               string `json:"black"`
+	Red                 string `json:"red"`
+	Green               string `json:"green"`
+	Yellow              string `json:"yellow"`
+	Blue                string `json:"blue"`
+	Purple              string `json:"purple"`
+	Cyan                string `json:"cyan"`
+	White               string `json:"white"`
+	BrightBlack         string `json:"brightBlack"`
+	BrightRed           string `json:"brightRed"`
+	BrightGreen         string `json:"brightGreen"`
+	BrightYellow        string `json:"brightYellow"`
+	BrightBlue          string `json:"brightBlue"`
+	BrightPurple        string `json:"brightPurple"`
+	BrightCyan          string `json:"brightCyan"`
+	BrightWhite         string `json:"brightWhite"`
+}
+
+// exportWindowsTerminal writes a scheme for the "schemes" list of
+// Windows Terminal's settings.json
+func exportWindowsTerminal(theme Theme) ([]byte, error) {
+	p := make([]string, 16)
+	for i, color := range theme.Palette {
+		p[i] = strings.ToUpper(hex8(color))
+	}
+	scheme := windowsTerminalScheme{
+		Name:       theme.Name,
+		Background: strings.ToUpper(hex8(theme.Background)),
+		Foreground: strings.ToUpper(hex8(theme.Foreground)),
+		Black:      p[0], Red: p[1], Green: p[2], Yellow: p[3],
+		Blue: p[4], Purple: p[5], Cyan: p[6], White: p[7],
+		BrightBlack: p[8], BrightRed: p[9], BrightGreen: p[10], BrightYellow: p[11],
+		BrightBlue: p[12], BrightPurple: p[13], BrightCyan: p[14], BrightWhite: p[15],
+	}
+	if theme.Cursor != nil {
+		scheme.CursorColor = strings.ToUpper(hex8(*theme.Cursor))
+	}
+	if theme.Selection != nil {
+		scheme.SelectionBackground = strings.ToUpper(hex8(*theme.Selection))
+	}
+
+	data, err := json.MarshalIndent(scheme, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// exportBase16 writes a base16 YAML scheme. base16 describes roles rather
+// than ANSI slots, so the mapping is the inverse of base16-shell's: the
+// accents come from the normal colors and the grays in between the
+// background and foreground are interpolated in OKLab.
+func exportBase16(theme Theme) string {
+	p := theme.Palette
+	bg, fg := theme.Background, theme.Foreground
+	selection := orDefault(theme.Selection, LerpOKLab16(bg, fg, 0.2))
+
+	bases := []RGB16{
+		bg,                               // base00 default background
+		LerpOKLab16(bg, fg, 0.1),         // base01 lighter background
+		selection,                        // base02 selection background
+		p[8],                             // base03 comments, bright black
+		LerpOKLab16(p[8], fg, 0.5),       // base04 dark foreground
+		fg,                               // base05 default foreground
+		LerpOKLab16(fg, p[15], 0.5),      // base06 light foreground
+		p[15],                            // base07 lightest foreground
+		p[1],                             // base08 red
+		LerpOKLab16(p[1], p[3], 0.5),     // base09 orange
+		p[3],                             // base0A yellow
+		p[2],                             // base0B green
+		p[6],                             // base0C cyan
+		p[4],                             // base0D blue
+		p[5],                             // base0E magenta
+		LerpOKLab16(p[1], RGB16{}, 0.35), // base0F brown
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "scheme: \"%s\"\nauthor: \"color\"\n", theme.Name)
+	for i, color := range bases {
+		fmt.Fprintf(&b, "base%02X: \"%s\"\n", i, strings.TrimPrefix(hex8(color), "#"))
+	}
+	return b.String()
+}
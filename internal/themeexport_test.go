@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// exportTestTheme is a fixed theme exercising every field an exporter
+// reads, with channels that only round-trip through 16 bits
+func exportTestTheme() Theme {
+	cursor := RGB16{R: 0xf8f8, G: 0x2828, B: 0x7272}
+	selection := RGB{0x44, 0x47, 0x5a}.To16()
+	palette := make([]RGB16, 16)
+	for i := range palette {
+		v := uint16(i * 4369) // 0 to 65535 in 16 steps
+		palette[i] = RGB16{R: v, G: 65535 - v, B: uint16(i*1000 + 123)}
+	}
+	return Theme{
+		Name:       "golden",
+		Background: RGB16{R: 0x1e1e, G: 0x2a2a, B: 0x3b3c},
+		Foreground: RGB{0xe6, 0xe6, 0xe6}.To16(),
+		Palette:    palette,
+		Created:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Cursor:     &cursor,
+		Selection:  &selection,
+	}
+}
+
+func TestExportThemeGolden(t *testing.T) {
+	for _, format := range ExportFormats {
+		t.Run(format, func(t *testing.T) {
+			got, err := ExportTheme(exportTestTheme(), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "export-"+format+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s export differs from %s (run go test -update if intended):\n%s", format, golden, got)
+			}
+		})
+	}
+}
+
+func TestExportThemeDefaultPalette(t *testing.T) {
+	theme := exportTestTheme()
+	theme.Palette = nil
+	withDefault := exportTestTheme()
+	withDefault.Palette = DefaultPalette
+
+	for _, format := range ExportFormats {
+		got, err := ExportTheme(theme, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := ExportTheme(withDefault, format)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: a theme without a palette isn't exported with the default one", format)
+		}
+	}
+}
+
+func TestExportThemeErrors(t *testing.T) {
+	theme := exportTestTheme()
+	if _, err := ExportTheme(theme, "terminal.app"); err == nil {
+		t.Error("unknown format was accepted")
+	}
+	theme.Palette = theme.Palette[:8]
+	if _, err := ExportTheme(theme, "kitty"); err == nil {
+		t.Error("8-color palette was accepted")
+	}
+}
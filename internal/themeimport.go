@@ -0,0 +1,676 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ThemeFormats lists the scheme formats themes can be imported from
+var ThemeFormats = []string{"itermcolors", "base16", "alacritty", "windows-terminal", "xresources"}
+
+// ImportResult is a theme read from a scheme file, with the keys of the
+// file the theme model has no place for
+type ImportResult struct {
+	Theme       Theme
+	Format      string
+	Unsupported []string
+}
+
+// ImportThemes parses a scheme file. Windows Terminal settings may hold
+// several schemes; every other format yields one theme. An empty format is
+// detected from the file name and content.
+func ImportThemes(path string, data []byte, format string) ([]ImportResult, error) {
+	if format == "" {
+		var err error
+		if format, err = DetectThemeFormat(path, data); err != nil {
+			return nil, err
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	switch format {
+	case "itermcolors":
+		return single(importITermColors(path, name, data))
+	case "base16":
+		return single(importBase16(path, name, data))
+	case "alacritty":
+		return single(importAlacritty(path, name, data))
+	case "windows-terminal":
+		return importWindowsTerminal(path, name, data)
+	case "xresources":
+		return single(importXresources(path, name, data))
+	}
+	return nil, fmt.Errorf("unknown theme format %q (expected %s)", format, strings.Join(ThemeFormats, ", "))
+}
+
+func single(result ImportResult, err error) ([]ImportResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []ImportResult{result}, nil
+}
+
+// DetectThemeFormat guesses a scheme format from the file extension, then
+// from the content
+func DetectThemeFormat(path string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".itermcolors":
+		return "itermcolors", nil
+	case ".yaml", ".yml":
+		return "base16", nil
+	case ".toml":
+		return "alacritty", nil
+	case ".json":
+		return "windows-terminal", nil
+	case ".xresources", ".xdefaults":
+		return "xresources", nil
+	}
+
+	text := string(data)
+	switch {
+	case strings.Contains(text, "<plist"):
+		return "itermcolors", nil
+	case strings.HasPrefix(strings.TrimSpace(string(stripJSONComments(data))), "{"):
+		return "windows-terminal", nil
+	case strings.Contains(text, "base00"):
+		return "base16", nil
+	case strings.Contains(text, "[colors"):
+		return "alacritty", nil
+	case strings.Contains(text, "color0") || strings.Contains(text, "*background"):
+		return "xresources", nil
+	}
+	return "", fmt.Errorf("%s: can't detect the theme format (use --format with one of %s)", path, strings.Join(ThemeFormats, ", "))
+}
+
+// themeBuilder collects the colors of a scheme and validates them
+type themeBuilder struct {
+	file        string
+	theme       Theme
+	palette     [16]*RGB16
+	seen        map[string]bool
+	unsupported []string
+	errs        []error
+}
+
+func newThemeBuilder(file string) *themeBuilder {
+	return &themeBuilder{file: file, seen: map[string]bool{}}
+}
+
+// set parses value into a theme slot: "background", "foreground", "cursor",
+// "cursor_text", "selection", "selection_text" or "color0" to "color15".
+// where identifies the key in error messages.
+func (b *themeBuilder) set(slot, value, where string) {
+	color, err := parseSchemeColor(value)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("%s: %s: %v", b.file, where, err))
+		b.seen[slot] = true // Reported already; not missing as well
+		return
+	}
+	b.setColor(slot, color)
+}
+
+func (b *themeBuilder) setColor(slot string, color RGB16) {
+	switch slot {
+	case "background":
+		b.theme.Background = color
+	case "foreground":
+		b.theme.Foreground = color
+	case "cursor":
+		b.theme.Cursor = &color
+	case "cursor_text":
+		b.theme.CursorText = &color
+	case "selection":
+		b.theme.Selection = &color
+	case "selection_text":
+		b.theme.SelectionText = &color
+	default:
+		index, _ := strconv.Atoi(strings.TrimPrefix(slot, "color"))
+		b.palette[index] = &color
+	}
+	b.seen[slot] = true
+}
+
+// unsupportedKey records a key of the file the theme model can't hold
+func (b *themeBuilder) unsupportedKey(key string) {
+	b.unsupported = append(b.unsupported, key)
+}
+
+// result validates that every required color was set
+func (b *themeBuilder) result(name, format string, slotNames func(slot string) string) (ImportResult, error) {
+	var missing []string
+	for _, slot := range []string{"background", "foreground"} {
+		if !b.seen[slot] {
+			missing = append(missing, slotNames(slot))
+		}
+	}
+	for i, color := range b.palette {
+		if color == nil {
+			missing = append(missing, slotNames(fmt.Sprintf("color%d", i)))
+		}
+	}
+	if len(missing) > 0 {
+		b.errs = append(b.errs, fmt.Errorf("%s: missing %s", b.file, strings.Join(missing, ", ")))
+	}
+	if len(b.errs) > 0 {
+		return ImportResult{}, errors.Join(b.errs...)
+	}
+
+	b.theme.Name = name
+	b.theme.Palette = make([]RGB16, len(b.palette))
+	for i, color := range b.palette {
+		b.theme.Palette[i] = *color
+	}
+	return ImportResult{Theme: b.theme, Format: format, Unsupported: b.unsupported}, nil
+}
+
+// parseSchemeColor parses the color notations used by scheme files:
+// "#rrggbb", "rrggbb", "0xrrggbb" and X11 "rgb:r/g/b"
+func parseSchemeColor(value string) (RGB16, error) {
+	s := strings.ToLower(strings.Trim(strings.TrimSpace(value), `"'`))
+
+	if spec, ok := strings.CutPrefix(s, "rgb:"); ok {
+		parts := strings.Split(spec, "/")
+		if len(parts) != 3 {
+			return RGB16{}, fmt.Errorf("invalid color %q", value)
+		}
+		var channels [3]uint16
+		for i, part := range parts {
+			v, err := strconv.ParseUint(part, 16, 16)
+			if err != nil || len(part) == 0 || len(part) > 4 {
+				return RGB16{}, fmt.Errorf("invalid color %q", value)
+			}
+			// Scale 1-4 hex digits to the full 16-bit range
+			channels[i] = uint16(v * 65535 / (1<<(4*len(part)) - 1))
+		}
+		return RGB16{R: channels[0], G: channels[1], B: channels[2]}, nil
+	}
+
+	if hex, ok := strings.CutPrefix(s, "0x"); ok {
+		s = "#" + hex
+	} else if len(s) == 6 && !strings.HasPrefix(s, "#") {
+		s = "#" + s
+	}
+	color, err := ParseColor16(s)
+	if err != nil {
+		return RGB16{}, fmt.Errorf("invalid color %q", value)
+	}
+	return color, nil
+}
+
+// iTermSlots maps .itermcolors keys to theme slots
+var iTermSlots = map[string]string{
+	"Background Color":    "background",
+	"Foreground Color":    "foreground",
+	"Cursor Color":        "cursor",
+	"Cursor Text Color":   "cursor_text",
+	"Selection Color":     "selection",
+	"Selected Text Color": "selection_text",
+}
+
+// plistNode is a generic XML element of a property list
+type plistNode struct {
+	XMLName xml.Name
+	Content string      `xml:",chardata"`
+	Nodes   []plistNode `xml:",any"`
+}
+
+// importITermColors reads an iTerm2 .itermcolors property list, whose
+// colors are dictionaries of float components
+func importITermColors(file, name string, data []byte) (ImportResult, error) {
+	var plist plistNode
+	if err := xml.Unmarshal(data, &plist); err != nil {
+		return ImportResult{}, fmt.Errorf("%s: invalid property list: %v", file, err)
+	}
+	if len(plist.Nodes) != 1 || plist.Nodes[0].XMLName.Local != "dict" {
+		return ImportResult{}, fmt.Errorf("%s: expected a dictionary of colors", file)
+	}
+
+	b := newThemeBuilder(file)
+	entries := plist.Nodes[0].Nodes
+	for i := 0; i+1 < len(entries); i += 2 {
+		key := strings.TrimSpace(entries[i].Content)
+		value := entries[i+1]
+
+		slot, ok := iTermSlots[key]
+		var index int
+		if _, err := fmt.Sscanf(key, "Ansi %d Color", &index); err == nil && index >= 0 && index < 16 {
+			slot, ok = fmt.Sprintf("color%d", index), true
+		}
+		if !ok || value.XMLName.Local != "dict" {
+			b.unsupportedKey(key)
+			continue
+		}
+
+		var components [3]float64
+		found := 0
+		for j := 0; j+1 < len(value.Nodes); j += 2 {
+			component := strings.TrimSpace(value.Nodes[j].Content)
+			text := strings.TrimSpace(value.Nodes[j+1].Content)
+			switch component {
+			case "Red Component", "Green Component", "Blue Component":
+				v, err := strconv.ParseFloat(text, 64)
+				if err != nil {
+					b.errs = append(b.errs, fmt.Errorf("%s: %s: invalid %s %q", file, key, component, text))
+					continue
+				}
+				components[map[string]int{"Red Component": 0, "Green Component": 1, "Blue Component": 2}[component]] = v
+				found++
+			case "Color Space":
+				if text != "sRGB" {
+					b.unsupportedKey(fmt.Sprintf("%s color space %s (read as sRGB)", key, text))
+				}
+			case "Alpha Component":
+			default:
+				b.unsupportedKey(key + " " + component)
+			}
+		}
+		if found != 3 {
+			b.errs = append(b.errs, fmt.Errorf("%s: %s: expected red, green and blue components", file, key))
+			continue
+		}
+		b.setColor(slot, RGB16{
+			R: toChannel16(components[0]),
+			G: toChannel16(components[1]),
+			B: toChannel16(components[2]),
+		})
+	}
+
+	return b.result(name, "itermcolors", func(slot string) string {
+		for key, s := range iTermSlots {
+			if s == slot {
+				return key
+			}
+		}
+		return "Ansi " + strings.TrimPrefix(slot, "color") + " Color"
+	})
+}
+
+// base16Slots maps base16 keys to ANSI colors, following base16-shell
+var base16Slots = []struct{ slot, key string }{
+	{"background", "base00"}, {"foreground", "base05"},
+	{"color0", "base00"}, {"color1", "base08"}, {"color2", "base0B"}, {"color3", "base0A"},
+	{"color4", "base0D"}, {"color5", "base0E"}, {"color6", "base0C"}, {"color7", "base05"},
+	{"color8", "base03"}, {"color9", "base08"}, {"color10", "base0B"}, {"color11", "base0A"},
+	{"color12", "base0D"}, {"color13", "base0E"}, {"color14", "base0C"}, {"color15", "base07"},
+	{"selection", "base02"}, {"cursor", "base05"},
+}
+
+// base24Bright replaces the bright colors when a base24 scheme defines them
+var base24Bright = []struct{ slot, key string }{
+	{"color8", "base02"}, {"color9", "base12"}, {"color10", "base14"}, {"color11", "base13"},
+	{"color12", "base16"}, {"color13", "base17"}, {"color14", "base15"},
+}
+
+var base16Key = regexp.MustCompile(`^base(0[0-9A-Fa-f]|1[0-7])$`)
+
+// importBase16 reads a base16 or base24 YAML scheme, either with the base
+// keys at the top level or nested under palette:
+func importBase16(file, name string, data []byte) (ImportResult, error) {
+	b := newThemeBuilder(file)
+	colors := map[string]RGB16{}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || text == "---" {
+			continue
+		}
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			b.errs = append(b.errs, fmt.Errorf("%s:%d: expected key: value", file, line))
+			continue
+		}
+		key, value = strings.TrimSpace(key), yamlScalar(value)
+
+		switch {
+		case key == "scheme" || key == "name":
+			if value != "" {
+				name = value
+			}
+		case key == "author" || key == "slug" || key == "system" || key == "variant" || key == "description":
+		case key == "palette" && value == "":
+		case base16Key.MatchString(key):
+			color, err := parseSchemeColor(value)
+			if err != nil {
+				b.errs = append(b.errs, fmt.Errorf("%s:%d: %s: %v", file, line, key, err))
+				continue
+			}
+			colors["base"+strings.ToUpper(key[4:])] = color
+		default:
+			b.unsupportedKey(key)
+		}
+	}
+
+	for _, m := range base16Slots {
+		if color, ok := colors[m.key]; ok {
+			b.setColor(m.slot, color)
+		}
+	}
+	if _, ok := colors["base12"]; ok {
+		for _, m := range base24Bright {
+			if color, ok := colors[m.key]; ok {
+				b.setColor(m.slot, color)
+			}
+		}
+	}
+
+	return b.result(name, "base16", func(slot string) string {
+		for _, m := range base16Slots {
+			if m.slot == slot {
+				return m.key
+			}
+		}
+		return slot
+	})
+}
+
+// yamlScalar unquotes a YAML scalar, dropping trailing comments
+func yamlScalar(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if strings.HasPrefix(value, "#") {
+		return ""
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// ansiKeys are the color names used by Alacritty and Windows Terminal
+var ansiKeys = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// importAlacritty reads the [colors] tables of an Alacritty TOML file
+func importAlacritty(file, name string, data []byte) (ImportResult, error) {
+	root, err := parseTOML(file, string(data))
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	b := newThemeBuilder(file)
+	colors := root.table("colors")
+	if colors == nil {
+		return ImportResult{}, fmt.Errorf("%s: no [colors] table", file)
+	}
+
+	get := func(t *tomlTable, section, key, slot string) {
+		v, ok := t.get(key)
+		if !ok {
+			return
+		}
+		where := fmt.Sprintf("line %d: colors.%s.%s", v.Line, section, key)
+		s, ok := v.Value.(string)
+		if !ok {
+			b.errs = append(b.errs, fmt.Errorf("%s: %s: expected a string", file, where))
+			return
+		}
+		if strings.HasPrefix(s, "Cell") {
+			b.unsupportedKey(fmt.Sprintf("colors.%s.%s = %s", section, key, s))
+			return
+		}
+		b.set(slot, s, where)
+	}
+
+	sections := []struct {
+		name  string
+		slots map[string]string
+	}{
+		{"primary", map[string]string{"background": "background", "foreground": "foreground"}},
+		{"cursor", map[string]string{"cursor": "cursor", "text": "cursor_text"}},
+		{"selection", map[string]string{"background": "selection", "text": "selection_text"}},
+		{"normal", nil},
+		{"bright", nil},
+	}
+	for _, section := range sections {
+		t := colors.table(section.name)
+		if t == nil {
+			continue
+		}
+		slots := section.slots
+		if slots == nil {
+			slots = map[string]string{}
+			offset := 0
+			if section.name == "bright" {
+				offset = 8
+			}
+			for i, key := range ansiKeys {
+				slots[key] = fmt.Sprintf("color%d", i+offset)
+			}
+		}
+		for _, key := range t.Keys {
+			if slot, ok := slots[key]; ok {
+				get(t, section.name, key, slot)
+			}
+		}
+		for _, key := range t.unused() {
+			b.unsupportedKey("colors." + section.name + "." + key)
+		}
+	}
+	for _, key := range colors.unused() {
+		b.unsupportedKey("colors." + key)
+	}
+	for _, key := range root.unused() {
+		b.unsupportedKey(key)
+	}
+
+	return b.result(name, "alacritty", func(slot string) string {
+		if strings.HasPrefix(slot, "color") {
+			i, _ := strconv.Atoi(slot[5:])
+			section := "normal"
+			if i >= 8 {
+				section, i = "bright", i-8
+			}
+			return "colors." + section + "." + ansiKeys[i]
+		}
+		return "colors.primary." + slot
+	})
+}
+
+// windowsTerminalSlots maps Windows Terminal scheme keys to theme slots
+var windowsTerminalSlots = map[string]string{
+	"background":          "background",
+	"foreground":          "foreground",
+	"cursorColor":         "cursor",
+	"selectionBackground": "selection",
+}
+
+func init() {
+	for i, key := range ansiKeys {
+		if key == "magenta" {
+			key = "purple"
+		}
+		windowsTerminalSlots[key] = fmt.Sprintf("color%d", i)
+		windowsTerminalSlots["bright"+strings.ToUpper(key[:1])+key[1:]] = fmt.Sprintf("color%d", i+8)
+	}
+}
+
+// importWindowsTerminal reads a Windows Terminal scheme object, or every
+// scheme in the "schemes" list of a settings.json. Like Windows Terminal,
+// it accepts comments and trailing commas.
+func importWindowsTerminal(file, name string, data []byte) ([]ImportResult, error) {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(stripJSONComments(data), &document); err != nil {
+		return nil, fmt.Errorf("%s: invalid JSON: %v", file, err)
+	}
+
+	var schemes []map[string]json.RawMessage
+	if raw, ok := document["schemes"]; ok {
+		if err := json.Unmarshal(raw, &schemes); err != nil {
+			return nil, fmt.Errorf("%s: schemes: %v", file, err)
+		}
+		if len(schemes) == 0 {
+			return nil, fmt.Errorf("%s: no schemes", file)
+		}
+	} else {
+		schemes = []map[string]json.RawMessage{document}
+	}
+
+	var results []ImportResult
+	var errs []error
+	for i, scheme := range schemes {
+		b := newThemeBuilder(file)
+		schemeName := name
+		if len(schemes) > 1 {
+			schemeName = fmt.Sprintf("%s-%d", name, i+1)
+		}
+
+		keys := make([]string, 0, len(scheme))
+		for key := range scheme {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			var value string
+			if err := json.Unmarshal(scheme[key], &value); err != nil {
+				b.unsupportedKey(key)
+				continue
+			}
+			if key == "name" {
+				schemeName = value
+				continue
+			}
+			slot, ok := windowsTerminalSlots[key]
+			if !ok {
+				b.unsupportedKey(key)
+				continue
+			}
+			b.set(slot, value, key)
+		}
+
+		result, err := b.result(schemeName, "windows-terminal", func(slot string) string {
+			for key, s := range windowsTerminalSlots {
+				if s == slot {
+					return key
+				}
+			}
+			return slot
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scheme %s: %w", schemeName, err))
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// stripJSONComments turns JSON with comments (JSONC) into plain JSON,
+// blanking // and /* */ comments and dropping commas before a closing
+// bracket. Strings are left untouched, and offsets in errors still hold.
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+	comma := -1 // Offset of a comma that may turn out to be trailing
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; {
+		case c == '"':
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+			comma = -1
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := len(out) // An unterminated comment runs to the end
+			if j := bytes.Index(out[i+2:], []byte("*/")); j >= 0 {
+				end = i + 2 + j + 2
+			}
+			for ; i < end; i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			i--
+		case c == ',':
+			comma = i
+		case c == '}' || c == ']':
+			if comma >= 0 {
+				out[comma] = ' '
+			}
+			comma = -1
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			comma = -1
+		}
+	}
+	return out
+}
+
+// importXresources reads *background, *foreground, *cursorColor and
+// *color0-15 resources, expanding #define macros
+func importXresources(file, name string, data []byte) (ImportResult, error) {
+	b := newThemeBuilder(file)
+	defines := map[string]string{}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "!") {
+			continue
+		}
+		if directive, ok := strings.CutPrefix(text, "#"); ok {
+			fields := strings.Fields(directive)
+			if len(fields) == 3 && fields[0] == "define" {
+				defines[fields[1]] = fields[2]
+			} else {
+				b.unsupportedKey(text)
+			}
+			continue
+		}
+
+		resource, value, ok := strings.Cut(text, ":")
+		if !ok {
+			b.errs = append(b.errs, fmt.Errorf("%s:%d: expected resource: value", file, line))
+			continue
+		}
+		resource, value = strings.TrimSpace(resource), strings.TrimSpace(value)
+		if expanded, ok := defines[value]; ok {
+			value = expanded
+		}
+
+		// The attribute is the last component of the resource name
+		attribute := resource[strings.LastIndexAny(resource, ".*")+1:]
+		slot := ""
+		switch attribute {
+		case "background", "foreground":
+			slot = attribute
+		case "cursorColor":
+			slot = "cursor"
+		default:
+			var index int
+			if _, err := fmt.Sscanf(attribute, "color%d", &index); err == nil && index >= 0 && index < 16 &&
+				attribute == fmt.Sprintf("color%d", index) {
+				slot = attribute
+			}
+		}
+		if slot == "" {
+			b.unsupportedKey(resource)
+			continue
+		}
+		b.set(slot, value, fmt.Sprintf("line %d: %s", line, resource))
+	}
+
+	return b.result(name, "xresources", func(slot string) string {
+		if slot == "cursor" {
+			return "*cursorColor"
+		}
+		return "*" + slot
+	})
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var draculaPalette = []string{
+	"#21222c", "#ff5555", "#50fa7b", "#f1fa8c", "#bd93f9", "#ff79c6", "#8be9fd", "#f8f8f2",
+	"#6272a4", "#ff6e6e", "#69ff94", "#ffffa5", "#d6acff", "#ff92df", "#a4ffff", "#ffffff",
+}
+
+var oceanPalette = []string{
+	"#2b303b", "#bf616a", "#a3be8c", "#ebcb8b", "#8fa1b3", "#b48ead", "#96b5b4", "#c0c5ce",
+	"#65737e", "#bf616a", "#a3be8c", "#ebcb8b", "#8fa1b3", "#b48ead", "#96b5b4", "#eff1f5",
+}
+
+// color16 parses a hex color for comparison with imported colors
+func color16(t *testing.T, text string) RGB16 {
+	t.Helper()
+	color, err := ParseColor(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return color.To16()
+}
+
+func TestImportThemes(t *testing.T) {
+	tests := []struct {
+		file        string
+		format      string
+		name        string
+		background  string
+		foreground  string
+		cursor      string
+		palette     []string
+		unsupported []string
+	}{
+		{"dracula.itermcolors", "itermcolors", "dracula", "#282a36", "#f8f8f2", "#f8f8f2", draculaPalette, []string{"Link Color"}},
+		{"dracula-plist", "itermcolors", "dracula-plist", "#282a36", "#f8f8f2", "#f8f8f2", draculaPalette, []string{"Link Color"}},
+		{"dracula.toml", "alacritty", "dracula", "#282a36", "#f8f8f2", "#f8f8f2", draculaPalette,
+			[]string{"colors.primary.dim_foreground", "colors.cursor.text = CellBackground", "colors.selection.text = CellForeground", "window"}},
+		{"dracula-alacritty", "alacritty", "dracula-alacritty", "#282a36", "#f8f8f2", "#f8f8f2", draculaPalette,
+			[]string{"colors.primary.dim_foreground", "colors.cursor.text = CellBackground", "colors.selection.text = CellForeground", "window"}},
+		{"dracula.json", "windows-terminal", "Dracula", "#282a36", "#f8f8f2", "#f8f8f2", draculaPalette, []string{"cursorShape"}},
+		{"dracula.Xresources", "xresources", "dracula", "#282a36", "#f8f8f2", "#f8f8f2", draculaPalette, []string{"*.font"}},
+		{"dracula-xresources", "xresources", "dracula-xresources", "#282a36", "#f8f8f2", "#f8f8f2", draculaPalette, []string{"*.font"}},
+		{"base16-ocean.yaml", "base16", "Ocean", "#2b303b", "#c0c5ce", "", oceanPalette, []string{"homepage"}},
+		{"base16-ocean", "base16", "Ocean", "#2b303b", "#c0c5ce", "", oceanPalette, []string{"homepage"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join("testdata", "import", tt.file)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format, err := DetectThemeFormat(path, data); err != nil || format != tt.format {
+				t.Fatalf("detected %q, %v; want %q", format, err, tt.format)
+			}
+			results, err := ImportThemes(path, data, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("got %d themes, want 1", len(results))
+			}
+
+			result := results[0]
+			theme := result.Theme
+			if theme.Name != tt.name {
+				t.Errorf("name is %q, want %q", theme.Name, tt.name)
+			}
+			if want := color16(t, tt.background); theme.Background != want {
+				t.Errorf("background is %v, want %v", theme.Background, want)
+			}
+			if want := color16(t, tt.foreground); theme.Foreground != want {
+				t.Errorf("foreground is %v, want %v", theme.Foreground, want)
+			}
+			if tt.cursor != "" {
+				if want := color16(t, tt.cursor); theme.Cursor == nil || *theme.Cursor != want {
+					t.Errorf("cursor is %v, want %v", theme.Cursor, want)
+				}
+			}
+			if len(theme.Palette) != 16 {
+				t.Fatalf("palette has %d colors, want 16", len(theme.Palette))
+			}
+			for i, text := range tt.palette {
+				if want := color16(t, text); theme.Palette[i] != want {
+					t.Errorf("color %d is %v, want %v", i, theme.Palette[i], want)
+				}
+			}
+			if !slices.Equal(result.Unsupported, tt.unsupported) {
+				t.Errorf("unsupported keys are %q, want %q", result.Unsupported, tt.unsupported)
+			}
+		})
+	}
+}
+
+func TestImportWindowsTerminalSettings(t *testing.T) {
+	path := filepath.Join("testdata", "import", "settings.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := ImportThemes(path, data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Theme.Name)
+	}
+	if !slices.Equal(names, []string{"Dracula", "Dracula Dark"}) {
+		t.Fatalf("imported %q, want Dracula and Dracula Dark", names)
+	}
+	if want := color16(t, "#1e1f29"); results[1].Theme.Background != want {
+		t.Errorf("Dracula Dark background is %v, want %v", results[1].Theme.Background, want)
+	}
+}
+
+func TestImportMissingSlot(t *testing.T) {
+	path := filepath.Join("testdata", "import", "missing.itermcolors")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportThemes(path, data, ""); err == nil || !strings.Contains(err.Error(), "Ansi 5 Color") {
+		t.Errorf("importing a scheme without Ansi 5 Color returned %v", err)
+	}
+}
+
+func TestStripJSONComments(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{"a": 1}`, `{"a": 1}`},
+		{`{"a": 1, // one` + "\n" + `}`, `{"a": 1        ` + "\n" + `}`},
+		{`{"a": /* one */ 1}`, `{"a":           1}`},
+		{`{"a": "http://x/*y*/", }`, `{"a": "http://x/*y*/"  }`},
+		{`["a\"//", "b",]`, `["a\"//", "b" ]`},
+		{`{"a": 1 /* open`, `{"a": 1        `},
+	}
+	for _, tt := range tests {
+		if got := string(stripJSONComments([]byte(tt.in))); got != tt.want {
+			t.Errorf("stripJSONComments(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
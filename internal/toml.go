@@ -0,0 +1,541 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tomlValue is a parsed TOML value with the line it was defined on. Value
+// holds a string, int64, float64, bool, []tomlValue, *tomlTable or
+// []*tomlTable (array of tables).
+type tomlValue struct {
+	Value interface{}
+	Line  int
+}
+
+// tomlTable is a TOML table that remembers key order and which keys a
+// decoder has consumed, so unknown keys can be reported
+type tomlTable struct {
+	Line    int
+	Keys    []string
+	Values  map[string]tomlValue
+	used    map[string]bool
+	defined bool // Opened by its own [header] or written inline, not implied by a subtable
+}
+
+// TOMLError is a TOML syntax or validation error at a specific line
+type TOMLError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *TOMLError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+func newTOMLTable(line int) *tomlTable {
+	return &tomlTable{Line: line, Values: map[string]tomlValue{}, used: map[string]bool{}}
+}
+
+// get returns a value and marks the key as consumed
+func (t *tomlTable) get(key string) (tomlValue, bool) {
+	if t == nil {
+		return tomlValue{}, false
+	}
+	v, ok := t.Values[key]
+	if ok {
+		t.used[key] = true
+	}
+	return v, ok
+}
+
+// table returns a sub-table and marks it as consumed
+func (t *tomlTable) table(key string) *tomlTable {
+	v, ok := t.get(key)
+	if !ok {
+		return nil
+	}
+	sub, _ := v.Value.(*tomlTable)
+	return sub
+}
+
+// tables returns an array of tables and marks it as consumed
+func (t *tomlTable) tables(key string) []*tomlTable {
+	v, ok := t.get(key)
+	if !ok {
+		return nil
+	}
+	list, _ := v.Value.([]*tomlTable)
+	return list
+}
+
+// unused returns the keys no decoder has consumed, in file order
+func (t *tomlTable) unused() []string {
+	var keys []string
+	for _, k := range t.Keys {
+		if !t.used[k] {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (t *tomlTable) set(key string, v tomlValue) {
+	if _, exists := t.Values[key]; !exists {
+		t.Keys = append(t.Keys, key)
+	}
+	t.Values[key] = v
+}
+
+// tomlParser is a recursive-descent parser for the TOML subset used by the
+// configuration files: tables, arrays of tables, strings, integers, floats,
+// booleans, arrays and inline tables
+type tomlParser struct {
+	file string
+	src  string
+	pos  int
+	line int
+}
+
+// parseTOML parses a TOML document into its root table
+func parseTOML(file, src string) (*tomlTable, error) {
+	p := &tomlParser{file: file, src: src, line: 1}
+	root := newTOMLTable(0)
+	current := root
+
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return root, nil
+		}
+
+		if p.peek() == '[' {
+			table, err := p.parseHeader(root)
+			if err != nil {
+				return nil, err
+			}
+			current = table
+		} else {
+			if err := p.parseKeyValue(current); err != nil {
+				return nil, err
+			}
+		}
+
+		p.skipBlank(false)
+		if !p.eof() && p.peek() != '\n' {
+			return nil, p.errorf("unexpected %q after value", p.peek())
+		}
+	}
+}
+
+// parseTOMLValue parses a single value, as used for environment overrides
+func parseTOMLValue(file, src string) (tomlValue, error) {
+	p := &tomlParser{file: file, src: strings.TrimSpace(src), line: 1}
+	v, err := p.parseValue()
+	if err != nil {
+		return tomlValue{}, err
+	}
+	if !p.eof() {
+		return tomlValue{}, p.errorf("unexpected %q after value", p.peek())
+	}
+	return v, nil
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return &TOMLError{File: p.file, Line: p.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) advance() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skipBlank skips spaces and comments, and newlines when newlines is true
+func (p *tomlParser) skipBlank(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.advance()
+		case c == '\n' && newlines:
+			p.advance()
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// parseHeader parses [table] or [[array]] and returns the table it opens
+func (p *tomlParser) parseHeader(root *tomlTable) (*tomlTable, error) {
+	line := p.line
+	p.advance()
+	array := !p.eof() && p.peek() == '['
+	if array {
+		p.advance()
+	}
+
+	path, err := p.parseKeyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	p.skipBlank(false)
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return nil, p.errorf("expected %q to close table header", closing)
+	}
+	for range closing {
+		p.advance()
+	}
+
+	parent := root
+	for _, key := range path[:len(path)-1] {
+		if parent, err = p.descend(parent, key, line); err != nil {
+			return nil, err
+		}
+	}
+
+	last := path[len(path)-1]
+	existing, exists := parent.Values[last]
+	if array {
+		if exists {
+			list, ok := existing.Value.([]*tomlTable)
+			if !ok {
+				return nil, p.errorf("%q is already defined on line %d", last, existing.Line)
+			}
+			table := newTOMLTable(line)
+			parent.Values[last] = tomlValue{Value: append(list, table), Line: existing.Line}
+			return table, nil
+		}
+		table := newTOMLTable(line)
+		parent.set(last, tomlValue{Value: []*tomlTable{table}, Line: line})
+		return table, nil
+	}
+
+	if exists {
+		// [a.b] before [a] defines a implicitly; [a] may still follow once
+		if table, ok := existing.Value.(*tomlTable); ok && !table.defined {
+			table.defined = true
+			return table, nil
+		}
+		return nil, p.errorf("table %q is already defined on line %d", strings.Join(path, "."), existing.Line)
+	}
+	table := newTOMLTable(line)
+	table.defined = true
+	parent.set(last, tomlValue{Value: table, Line: line})
+	return table, nil
+}
+
+// descend returns the sub-table at key, creating it implicitly when missing.
+// For arrays of tables the most recent element is used.
+func (p *tomlParser) descend(parent *tomlTable, key string, line int) (*tomlTable, error) {
+	existing, exists := parent.Values[key]
+	if !exists {
+		table := newTOMLTable(line)
+		parent.set(key, tomlValue{Value: table, Line: line})
+		return table, nil
+	}
+	switch v := existing.Value.(type) {
+	case *tomlTable:
+		return v, nil
+	case []*tomlTable:
+		return v[len(v)-1], nil
+	}
+	return nil, p.errorf("%q is already defined as a value on line %d", key, existing.Line)
+}
+
+// parseKeyValue parses key = value into table
+func (p *tomlParser) parseKeyValue(table *tomlTable) error {
+	line := p.line
+	path, err := p.parseKeyPath()
+	if err != nil {
+		return err
+	}
+
+	p.skipBlank(false)
+	if p.eof() || p.peek() != '=' {
+		return p.errorf("expected '=' after key %q", strings.Join(path, "."))
+	}
+	p.advance()
+	p.skipBlank(false)
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range path[:len(path)-1] {
+		if table, err = p.descend(table, key, line); err != nil {
+			return err
+		}
+	}
+	last := path[len(path)-1]
+	if existing, exists := table.Values[last]; exists {
+		return &TOMLError{File: p.file, Line: line, Msg: fmt.Sprintf("key %q is already defined on line %d", last, existing.Line)}
+	}
+	table.set(last, value)
+	return nil
+}
+
+// parseKeyPath parses a possibly dotted key such as a.b."c d"
+func (p *tomlParser) parseKeyPath() ([]string, error) {
+	var path []string
+	for {
+		p.skipBlank(false)
+		if p.eof() {
+			return nil, p.errorf("expected key")
+		}
+
+		var key string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			v, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			key = v
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.advance()
+			}
+			if p.pos == start {
+				return nil, p.errorf("invalid character %q in key", p.peek())
+			}
+			key = p.src[start:p.pos]
+		}
+		path = append(path, key)
+
+		p.skipBlank(false)
+		if p.eof() || p.peek() != '.' {
+			return path, nil
+		}
+		p.advance()
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// parseValue parses any value at the current position
+func (p *tomlParser) parseValue() (tomlValue, error) {
+	line := p.line
+	if p.eof() {
+		return tomlValue{}, p.errorf("expected value")
+	}
+
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		s, err := p.parseString()
+		return tomlValue{Value: s, Line: line}, err
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+		p.advance()
+	}
+	word := p.src[start:p.pos]
+
+	switch word {
+	case "true":
+		return tomlValue{Value: true, Line: line}, nil
+	case "false":
+		return tomlValue{Value: false, Line: line}, nil
+	case "":
+		return tomlValue{}, p.errorf("expected value")
+	}
+
+	if n, ok := parseTOMLNumber(word); ok {
+		return tomlValue{Value: n, Line: line}, nil
+	}
+	return tomlValue{}, p.errorf("invalid value %q (strings must be quoted)", word)
+}
+
+var (
+	tomlDecimal = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	tomlFloat   = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+)
+
+// tomlPrefixed lists the integer forms with a base prefix. They take no sign.
+var tomlPrefixed = []struct {
+	prefix string
+	base   int
+	digits *regexp.Regexp
+}{
+	{"0x", 16, regexp.MustCompile(`^[0-9a-fA-F](_?[0-9a-fA-F])*$`)},
+	{"0o", 8, regexp.MustCompile(`^[0-7](_?[0-7])*$`)},
+	{"0b", 2, regexp.MustCompile(`^[01](_?[01])*$`)},
+}
+
+// parseTOMLNumber parses a TOML integer (decimal without leading zeros, or
+// 0x, 0o and 0b prefixed) or float (including inf and nan), returning an
+// int64 or float64
+func parseTOMLNumber(word string) (interface{}, bool) {
+	for _, form := range tomlPrefixed {
+		if digits, ok := strings.CutPrefix(word, form.prefix); ok {
+			if !form.digits.MatchString(digits) {
+				return nil, false
+			}
+			n, err := strconv.ParseInt(strings.ReplaceAll(digits, "_", ""), form.base, 64)
+			return n, err == nil
+		}
+	}
+
+	switch word {
+	case "inf", "+inf":
+		return math.Inf(1), true
+	case "-inf":
+		return math.Inf(-1), true
+	case "nan", "+nan", "-nan":
+		return math.NaN(), true
+	}
+
+	switch {
+	case tomlDecimal.MatchString(word):
+		n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64)
+		return n, err == nil
+	case tomlFloat.MatchString(word):
+		f, err := strconv.ParseFloat(strings.ReplaceAll(word, "_", ""), 64)
+		return f, err == nil
+	}
+	return nil, false
+}
+
+// parseString parses a basic "..." or literal '...' string
+func (p *tomlParser) parseString() (string, error) {
+	quote := p.advance()
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.advance()
+		if c == quote {
+			return b.String(), nil
+		}
+		if c != '\\' || quote == '\'' {
+			b.WriteByte(c)
+			continue
+		}
+
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		switch e := p.advance(); e {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(e)
+		case 'u', 'U':
+			size := 4
+			if e == 'U' {
+				size = 8
+			}
+			if p.pos+size > len(p.src) {
+				return "", p.errorf("invalid unicode escape")
+			}
+			code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(code)) {
+				return "", p.errorf("invalid unicode escape")
+			}
+			p.pos += size
+			b.WriteRune(rune(code))
+		default:
+			return "", p.errorf("invalid escape sequence \\%c", e)
+		}
+	}
+}
+
+// parseArray parses [a, b, ...], which may span several lines
+func (p *tomlParser) parseArray() (tomlValue, error) {
+	line := p.line
+	p.advance()
+	var items []tomlValue
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return tomlValue{}, &TOMLError{File: p.file, Line: line, Msg: "unterminated array"}
+		}
+		if p.peek() == ']' {
+			p.advance()
+			return tomlValue{Value: items, Line: line}, nil
+		}
+
+		item, err := p.parseValue()
+		if err != nil {
+			return tomlValue{}, err
+		}
+		items = append(items, item)
+
+		p.skipBlank(true)
+		if !p.eof() && p.peek() == ',' {
+			p.advance()
+		} else if !p.eof() && p.peek() != ']' {
+			return tomlValue{}, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// parseInlineTable parses { key = value, ... } on a single line
+func (p *tomlParser) parseInlineTable() (tomlValue, error) {
+	line := p.line
+	p.advance()
+	table := newTOMLTable(line)
+	table.defined = true
+	for {
+		p.skipBlank(false)
+		if p.eof() || p.peek() == '\n' {
+			return tomlValue{}, p.errorf("unterminated inline table")
+		}
+		if p.peek() == '}' {
+			p.advance()
+			return tomlValue{Value: table, Line: line}, nil
+		}
+
+		if err := p.parseKeyValue(table); err != nil {
+			return tomlValue{}, err
+		}
+
+		p.skipBlank(false)
+		if !p.eof() && p.peek() == ',' {
+			p.advance()
+		} else if !p.eof() && p.peek() != '}' {
+			return tomlValue{}, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
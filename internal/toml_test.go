@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// plain strips line numbers and bookkeeping from a parsed value so tests
+// can compare against ordinary Go values
+func plain(v interface{}) interface{} {
+	switch v := v.(type) {
+	case tomlValue:
+		return plain(v.Value)
+	case []tomlValue:
+		list := []interface{}{}
+		for _, item := range v {
+			list = append(list, plain(item))
+		}
+		return list
+	case *tomlTable:
+		table := map[string]interface{}{}
+		for key, value := range v.Values {
+			table[key] = plain(value)
+		}
+		return table
+	case []*tomlTable:
+		list := []interface{}{}
+		for _, table := range v {
+			list = append(list, plain(table))
+		}
+		return list
+	}
+	return v
+}
+
+type table = map[string]interface{}
+type list = []interface{}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want table
+	}{
+		{"empty", "", table{}},
+		{"comments", "# only a comment\n\n  # another\n", table{}},
+		{"strings", `a = "x\ty\u00e9"` + "\n" + `b = 'C:\path'`, table{"a": "x\tyé", "b": `C:\path`}},
+		{"booleans", "a = true\nb = false", table{"a": true, "b": false}},
+		{"decimal integers", "a = 42\nb = -17\nc = +3\nd = 0\ne = 1_000", table{"a": int64(42), "b": int64(-17), "c": int64(3), "d": int64(0), "e": int64(1000)}},
+		{"prefixed integers", "a = 0xff\nb = 0o17\nc = 0b101\nd = 0xdead_beef", table{"a": int64(255), "b": int64(15), "c": int64(5), "d": int64(0xdeadbeef)}},
+		{"floats", "a = 0.5\nb = -1.25\nc = 1e3\nd = 6.02E-2\ne = 1_0.5", table{"a": 0.5, "b": -1.25, "c": 1000.0, "d": 0.0602, "e": 10.5}},
+		{"infinities", "a = inf\nb = +inf\nc = -inf", table{"a": math.Inf(1), "b": math.Inf(1), "c": math.Inf(-1)}},
+		{"arrays", "a = [1, 2,\n  3, # comment\n]\nb = []", table{"a": list{int64(1), int64(2), int64(3)}, "b": list{}}},
+		{"inline tables", `a = { b = 1, c = "d" }`, table{"a": table{"b": int64(1), "c": "d"}}},
+		{"dotted keys", "a.b = 1\na.c = 2", table{"a": table{"b": int64(1), "c": int64(2)}}},
+		{"quoted keys", `"a b" = 1`, table{"a b": int64(1)}},
+		{"tables", "[a]\nx = 1\n[b.c]\ny = 2", table{"a": table{"x": int64(1)}, "b": table{"c": table{"y": int64(2)}}}},
+		{"super table after subtable", "[t.u]\nx = 1\n[t]\ny = 2", table{"t": table{"u": table{"x": int64(1)}, "y": int64(2)}}},
+		{"arrays of tables", "[[r]]\nn = 1\n[[r]]\nn = 2\n[r.s]\nm = 3", table{"r": list{table{"n": int64(1)}, table{"n": int64(2), "s": table{"m": int64(3)}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parseTOML("test.toml", tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := plain(root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLNaN(t *testing.T) {
+	for _, src := range []string{"a = nan", "a = +nan", "a = -nan"} {
+		root, err := parseTOML("test.toml", src)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if f, ok := root.Values["a"].Value.(float64); !ok || !math.IsNaN(f) {
+			t.Errorf("%s: got %#v", src, root.Values["a"].Value)
+		}
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+		msg  string
+	}{
+		{"leading zero", "a = 1\nb = 010", 2, `invalid value "010"`},
+		{"signed prefix", "a = -0x10", 1, `invalid value "-0x10"`},
+		{"bad hex digit", "a = 0xfg", 1, `invalid value "0xfg"`},
+		{"bad octal digit", "a = 0o8", 1, `invalid value "0o8"`},
+		{"bad binary digit", "a = 0b2", 1, `invalid value "0b2"`},
+		{"doubled underscore", "a = 1__0", 1, `invalid value "1__0"`},
+		{"trailing underscore", "a = 10_", 1, `invalid value "10_"`},
+		{"trailing dot", "a = 1.", 1, `invalid value "1."`},
+		{"leading dot", "a = .5", 1, `invalid value ".5"`},
+		{"doubled sign", "a = --inf", 1, `invalid value "--inf"`},
+		{"infinity spelled out", "a = infinity", 1, `invalid value "infinity"`},
+		{"overflow", "a = 9223372036854775808", 1, `invalid value`},
+		{"unquoted string", "\n\na = hello", 3, `strings must be quoted`},
+		{"duplicate key", "a = 1\n\na = 2", 3, `key "a" is already defined on line 1`},
+		{"duplicate table", "[t]\n[u]\n[t]", 3, `table "t" is already defined on line 1`},
+		{"table after inline table", "t = { a = 1 }\n[t]", 2, `table "t" is already defined on line 1`},
+		{"table over value", "t = 1\n[t.u]", 2, `"t" is already defined as a value on line 1`},
+		{"array of tables over table", "[t]\n[[t]]", 2, `"t" is already defined on line 1`},
+		{"unterminated string", "a = 1\nb = \"abc\nc = 2", 2, "unterminated string"},
+		{"unterminated array", "a = [1,\n2,\n", 1, "unterminated array"},
+		{"unterminated inline table", "a = { b = 1,\n", 1, "unterminated inline table"},
+		{"inline table across lines", "a = { b = 1\n}", 1, "expected ',' or '}' in inline table"},
+		{"unclosed header", "[t\nx = 1", 1, `expected "]" to close table header`},
+		{"missing equals", "a 1", 1, `expected '=' after key "a"`},
+		{"junk after value", "a = 1 2", 1, `unexpected '2' after value`},
+		{"bad escape", `a = "\q"`, 1, `invalid escape sequence \q`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOML("test.toml", tt.src)
+			var tomlErr *TOMLError
+			if !errors.As(err, &tomlErr) {
+				t.Fatalf("got %v, want a TOML error", err)
+			}
+			if tomlErr.Line != tt.line || !strings.Contains(tomlErr.Msg, tt.msg) {
+				t.Errorf("got line %d %q, want line %d containing %q", tomlErr.Line, tomlErr.Msg, tt.line, tt.msg)
+			}
+		})
+	}
+}
+
+func TestLightScalesMustBePositive(t *testing.T) {
+	for _, src := range []string{"[light]\nvalue_scale = 0", "[light]\nsaturation_scale = 0.0", "[light]\nvalue_scale = nan", "[light]\nvalue_scale = 1.5"} {
+		root, err := parseTOML("config.toml", src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := &configDecoder{file: "config.toml", noEnv: true}
+		d.decode(root, DefaultConfig())
+		if len(d.errs) == 0 {
+			t.Errorf("%q was accepted", src)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// TransitionOptions controls animated changes between colors
+type TransitionOptions struct {
+	Enabled  bool
+	Duration time.Duration
+	FPS      int
+}
+
+// DefaultTransitionOptions returns transition settings used when none are given
+func DefaultTransitionOptions() TransitionOptions {
+	return TransitionOptions{
+		Enabled:  false,
+		Duration: 300 * time.Millisecond,
+		FPS:      30,
+	}
+}
+
+// SetTransition changes how ApplyColor moves to a new color
+func (c *ColorManager) SetTransition(opts TransitionOptions) {
+	c.transition = opts
+}
+
+// ApplyColor sets the terminal color, animating from the current color when
+// transitions are enabled. A transition is abandoned as soon as another color
+// change starts in the same terminal session. Once the color is applied the
+// change is recorded in the color history.
+func (c *ColorManager) ApplyColor(target RGB, change ColorChange) error {
+	return c.ApplyColor16(target.To16(), change)
+}
+
+// ApplyColor16 is ApplyColor at full precision
+func (c *ColorManager) ApplyColor16(target RGB16, change ColorChange) error {
+	var old *RGB16
+	if last, ok := c.store.GetLastColor(); ok {
+		old = &last
+	}
+
+	applied, err := c.transitionTo(target)
+	if err != nil || !applied {
+		return err
+	}
+	// Remembered so `color theme save --from last` can capture it
+	c.store.SetLastColor(target)
+	c.recordEvent(change, old, target)
+	return nil
+}
+
+// transitionTo moves the terminal to target, reporting false when a newer
+// color change took over before it got there
+func (c *ColorManager) transitionTo(target RGB16) (bool, error) {
+	token := claimTransition()
+
+	opts := c.transition
+	if !opts.Enabled || opts.Duration <= 0 || opts.FPS <= 0 {
+		return true, c.SetITermColor16(target)
+	}
+
+	from, err := c.GetCurrentColor16()
+	if err != nil || from == target {
+		return true, c.SetITermColor16(target)
+	}
+
+	frames := int(opts.Duration.Seconds() * float64(opts.FPS))
+	if frames < 1 {
+		frames = 1
+	}
+	interval := opts.Duration / time.Duration(frames)
+	start := time.Now()
+
+	for i := 1; i <= frames; i++ {
+		if !ownsTransition(token) {
+			return false, nil // Superseded by a newer color change
+		}
+
+		if err := c.SetITermColor16(LerpOKLab16(from, target, float64(i)/float64(frames))); err != nil {
+			return false, err
+		}
+
+		// Schedule against the start time so slow frames don't stretch the animation
+		if wait := time.Until(start.Add(time.Duration(i) * interval)); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+
+	return true, nil
+}
+
+var sessionIDSanitizer = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// transitionTokenPath returns the per-session file recording the latest color change
+func transitionTokenPath() string {
+	session := os.Getenv("ITERM_SESSION_ID")
+	if session == "" {
+		session = os.Getenv("TERM_SESSION_ID")
+	}
+	if session == "" {
+		session = "default"
+	}
+	name := fmt.Sprintf("color-transition-%d-%s", os.Getuid(), sessionIDSanitizer.ReplaceAllString(session, "_"))
+	return filepath.Join(os.TempDir(), name)
+}
+
+// claimTransition marks this process as the owner of the session's color
+func claimTransition() string {
+	token := fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	os.WriteFile(transitionTokenPath(), []byte(token), 0600)
+	return token
+}
+
+// ownsTransition reports whether no newer color change has started
+func ownsTransition(token string) bool {
+	data, err := os.ReadFile(transitionTokenPath())
+	if err != nil {
+		return true // Without a token file there is nothing to cancel against
+	}
+	return string(data) == token
+}